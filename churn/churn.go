@@ -3,129 +3,310 @@ package churn
 import (
 	"context"
 	"fmt"
-	"math/rand"
+	"reflect"
+	"sync"
 	"time"
 
 	"github.com/ethereum/go-ethereum/log"
-	"github.com/status-im/status-scale/client"
 	"github.com/status-im/status-scale/cluster"
 	"github.com/status-im/status-scale/network"
 	"github.com/status-im/status-scale/utils"
 )
 
-func NewChurnSim(participants []*cluster.Client, params Params) *ChurnSim {
-	live := time.Duration(params.Period.Seconds()*params.ChurnRate) * time.Second
-	jitter := params.Period / time.Duration(2)
-	lth := len(participants)
-	liveSince := make([]time.Time, lth)
+// NewChurnSim creates simulation for participants of any type. Users request history from mail servers
+// when they are back online.
+func NewChurnSim(participants []cluster.Node, params Params) *ChurnSim {
+	model := params.Model
+	if model == nil {
+		live := time.Duration(params.Period.Seconds()*params.ChurnRate) * time.Second
+		jitter := params.Period / time.Duration(2)
+		model = NewUniform(live, jitter, params.Seed)
+	}
 	return &ChurnSim{
 		Params:       params,
-		live:         live,
-		total:        params.Period,
-		jitter:       jitter,
+		model:        model,
 		participants: participants,
-		offline:      make([]bool, lth),
-		offlineUntil: make([]time.Time, lth),
-		liveSince:    liveSince,
 	}
 }
 
+// Mode defines how participant goes offline.
+type Mode string
+
+const (
+	// Blackhole drops all traffic, process and its connections stay alive. This is the default.
+	Blackhole Mode = "blackhole"
+	// Pause freezes all processes with docker pause, like an app in background.
+	Pause Mode = "pause"
+	// Stop gracefully stops the container and starts it again.
+	Stop Mode = "stop"
+	// Kill kills the container, like an os that kills an app, and restarts it with the same data.
+	Kill Mode = "kill"
+)
+
+// Modes are all supported modes.
+var Modes = []Mode{Blackhole, Pause, Stop, Kill}
+
 type Params struct {
 	TargetAddrs []string
+	// Modes are assigned to participants in order, i-th participant uses mode i % len(Modes).
+	// Blackhole is used if empty.
+	Modes []Mode
 	// ChurnRate specifies part of time that peer is online
 	ChurnRate float64
 	Period    time.Duration
+	// Model overwrites ChurnRate and Period.
+	Model SessionModel
+	// Seed of the default model.
+	Seed int64
+	// History configures requests for missed messages when users are back online.
+	History History
+}
+
+// Transition is a change of participant state. Scheduled is the time when transition was due,
+// Applied is the time when it was completed.
+type Transition struct {
+	Peer      string
+	Mode      Mode
+	Online    bool
+	Scheduled time.Time
+	Applied   time.Time
+	Err       error
+}
+
+// Delay is the time between scheduled and applied transition.
+func (t Transition) Delay() time.Duration {
+	return t.Applied.Sub(t.Scheduled)
+}
+
+// Lateness returns mean and max delay of successful transitions.
+func Lateness(transitions []Transition) (mean, max time.Duration) {
+	var (
+		total time.Duration
+		count int
+	)
+	for _, t := range transitions {
+		if t.Err != nil {
+			continue
+		}
+		delay := t.Delay()
+		total += delay
+		count++
+		if delay > max {
+			max = delay
+		}
+	}
+	if count == 0 {
+		return 0, 0
+	}
+	return total / time.Duration(count), max
 }
 
 // ChurnSim controls live and offline period for each participant.
 type ChurnSim struct {
 	Params Params
 
-	live         time.Duration
-	total        time.Duration
-	jitter       time.Duration // jitter is a half of the total
-	participants []*cluster.Client
-	liveSince    []time.Time
-	offlineUntil []time.Time
-	offline      []bool
+	model        SessionModel
+	participants []cluster.Node
+
+	mu          sync.Mutex
+	transitions []Transition
+	// offline is set before participant is stopped and cleared after it is started
+	offline    map[int]bool
+	inboxes    map[int]*inbox
+	recoveries []Recovery
+}
+
+func (c *ChurnSim) mode(i int) Mode {
+	if len(c.Params.Modes) == 0 {
+		return Blackhole
+	}
+	return c.Params.Modes[i%len(c.Params.Modes)]
 }
 
-func (c *ChurnSim) Control(ctx context.Context) error {
+func (c *ChurnSim) setOffline(i int, offline bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.offline == nil {
+		c.offline = map[int]bool{}
+	}
+	c.offline[i] = offline
+}
+
+func (c *ChurnSim) isOffline(i int) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.offline[i]
+}
+
+// Run simulates churn until context is cancelled. Every participant has its own lifecycle, so that a slow
+// transition of one participant doesn't delay others. Participants that failed a transition are excluded
+// from the simulation. Once context is cancelled all participants are started.
+func (c *ChurnSim) Run(ctx context.Context) error {
+	begin := time.Now()
+	group := utils.NewGroup(ctx, len(c.participants))
 	for i := range c.participants {
-		err := c.control(ctx, i)
+		i := i
+		group.Run(func(ctx context.Context) error {
+			return c.lifecycle(ctx, i, begin)
+		})
+	}
+	err := group.Error()
+	// bring all participants back online after simulation was terminated
+	if serr := c.Start(context.Background()); serr != nil {
+		log.Error("failed to start participants after churn", "error", serr)
+	}
+	// count messages after the last offline period
+	for i, p := range c.participants {
+		if user, ok := p.(*cluster.Client); ok {
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			c.snapshot(ctx, i, user)
+			cancel()
+		}
+	}
+	return err
+}
+
+// Transitions returns all transitions in the order in which they were applied.
+func (c *ChurnSim) Transitions() []Transition {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Transition{}, c.transitions...)
+}
+
+// lifecycle alternates online and offline periods of i-th participant, starting from begin. Every period
+// is counted from the scheduled time of the previous transition, so that delays don't accumulate.
+func (c *ChurnSim) lifecycle(ctx context.Context, i int, begin time.Time) error {
+	p := c.participants[i]
+	online := true
+	period := c.model.Online(i, 0)
+	next := begin
+	for {
+		if period == Forever {
+			<-ctx.Done()
+			return nil
+		}
+		next = next.Add(period)
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return nil
+		case <-timer.C:
+		}
+		var err error
+		if online {
+			log.Debug("peer will be stopped", "peer", p.UID(), "mode", c.mode(i))
+			err = c.stop(ctx, i)
+		} else {
+			log.Debug("peer will be started", "peer", p.UID(), "mode", c.mode(i))
+			err = c.start(ctx, i)
+		}
+		if ctx.Err() != nil {
+			return nil
+		}
+		c.record(Transition{Peer: p.UID(), Mode: c.mode(i), Online: !online, Scheduled: next, Applied: time.Now(), Err: err})
 		if err != nil {
-			return err
+			log.Error("peer transition failed", "peer", p.UID(), "online", !online, "error", err)
+			return fmt.Errorf("%s: %v", p.UID(), err)
+		}
+		online = !online
+		if online {
+			c.history(ctx, i)
+			period = c.model.Online(i, next.Sub(begin))
+		} else {
+			period = c.model.Offline(i, next.Sub(begin))
+			log.Debug("peer will be offline", "peer", p.UID(), "duration", period)
 		}
 	}
-	return nil
 }
 
+func (c *ChurnSim) record(t Transition) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.transitions = append(c.transitions, t)
+}
+
+// Start concurrently starts all participants that are offline.
 func (c *ChurnSim) Start(ctx context.Context) error {
+	group := utils.NewGroup(ctx, len(c.participants))
 	for i := range c.participants {
-		_ = c.start(ctx, c.participants[i])
+		i := i
+		group.Run(func(ctx context.Context) error {
+			if !c.isOffline(i) {
+				return nil
+			}
+			if err := c.start(ctx, i); err != nil {
+				return err
+			}
+			c.history(ctx, i)
+			return nil
+		})
 	}
-	return nil
+	return group.Error()
 }
 
-// stop must prevent peer from receiving any traffic from peers in the same network
-func (c *ChurnSim) stop(ctx context.Context, p *cluster.Client) error {
-	return p.EnableConditions(ctx, network.Options{
+func (c *ChurnSim) options() network.Options {
+	return network.Options{
 		PacketLoss:  100,
 		TargetAddrs: c.Params.TargetAddrs,
-	})
+	}
 }
 
-// start must disable packet loss and trigger history request for a specific chat or all history
-// note(dshulyak) requesting all history will make latency higher but it is more releastic.
-func (c *ChurnSim) start(ctx context.Context, p *cluster.Client) error {
-	err := p.DisableConditions(ctx, network.Options{
-		PacketLoss:  100,
-		TargetAddrs: c.Params.TargetAddrs,
-	})
-	if err != nil {
-		return fmt.Errorf("failed to disable packet loss: %v", err)
-	}
-	log.Debug("trying to fetch messages from mail server", "peer", p.UID())
-	return utils.PollImmediateNoError(ctx, func(parent context.Context) error {
-		ctx, cancel := context.WithTimeout(parent, 5*time.Second)
-		defer cancel()
-		err := client.ChatClient(p.Rpc()).RequestAll(ctx)
-		if err != nil {
-			return fmt.Errorf("requesting messages failed: %v", err)
+// blackholed is true if conditions enabled by stop are active on the peer.
+func (c *ChurnSim) blackholed(p cluster.Node) bool {
+	opts := c.options()
+	for _, active := range p.ActiveConditions() {
+		if reflect.DeepEqual(active, opts) {
+			return true
 		}
-		log.Debug("fetched messages from mail server", "peer", p.UID())
-		return nil
-	}, 2*time.Second, 30*time.Second)
+	}
+	return false
 }
 
-// FIXME (dshulyak) rewrite in a concurrently friendly way
-func (c *ChurnSim) control(ctx context.Context, i int) error {
-	if !c.offline[i] && time.Since(c.liveSince[i]) > c.live {
-		log.Debug("peer will be stopped", "peer", c.participants[i].UID())
-		err := c.stop(ctx, c.participants[i])
-		if err != nil {
-			log.Error("stopping peer failed", "peer", c.participants[i].UID(), "error", err)
-			return err
-		}
-		log.Debug("peer is stopped", "peer", c.participants[i].UID())
-		offline := c.jitter
-		jitter := time.Duration(rand.Int63n(int64(c.jitter.Seconds()))*2) * time.Second
-		offline += jitter
-		log.Debug("peer will be offline", "peer", c.participants[i].UID(), "duration", offline)
-		c.offlineUntil[i] = time.Now().Add(offline)
-		c.offline[i] = true
-		return nil
-	}
-	if c.offline[i] && time.Now().Sub(c.offlineUntil[i]) > 0 {
-		log.Debug("peer will be started", "peer", c.participants[i].UID())
-		err := c.start(ctx, c.participants[i])
-		if err != nil {
-			log.Error("starting peer failed", "peer", c.participants[i].UID(), "error", err)
-			return err
+// stop must prevent peer from receiving any traffic from peers in the same network
+func (c *ChurnSim) stop(ctx context.Context, i int) error {
+	p := c.participants[i]
+	if _, ok := p.(*cluster.Client); ok {
+		c.inbox(i).lastSeen = time.Now()
+	}
+	// participant is marked before the transition, so that it is started even if transition was interrupted
+	c.setOffline(i, true)
+	switch mode := c.mode(i); mode {
+	case Blackhole:
+		return p.EnableConditions(ctx, c.options())
+	case Pause:
+		return p.Pause(ctx)
+	case Stop:
+		return p.Stop(ctx)
+	case Kill:
+		return p.Kill(ctx)
+	default:
+		return fmt.Errorf("unknown churn mode %s", mode)
+	}
+}
+
+// start must bring peer back, history is requested separately
+func (c *ChurnSim) start(ctx context.Context, i int) error {
+	p := c.participants[i]
+	var err error
+	switch mode := c.mode(i); mode {
+	case Blackhole:
+		if c.blackholed(p) {
+			err = p.DisableConditions(ctx, c.options())
 		}
-		log.Debug("peer is started", "peer", c.participants[i].UID())
-		c.liveSince[i] = time.Now()
-		c.offline[i] = false
+	case Pause:
+		err = p.Unpause(ctx)
+	case Stop, Kill:
+		err = p.Start(ctx)
+	default:
+		err = fmt.Errorf("unknown churn mode %s", mode)
+	}
+	if err != nil {
+		return fmt.Errorf("failed to start peer: %v", err)
+	}
+	c.setOffline(i, false)
+	if _, ok := p.(*cluster.Client); ok {
+		c.inbox(i).online = time.Now()
 	}
 	return nil
 }
@@ -0,0 +1,102 @@
+package churn
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/status-im/status-console-client/protocol/gethservice"
+	"github.com/stretchr/testify/require"
+
+	"github.com/status-im/status-scale/client"
+	"github.com/status-im/status-scale/cluster"
+	"github.com/status-im/status-scale/cluster/fakebackend"
+)
+
+// churnWithHistory stops second user, sends a message while it is offline and another one after it is back
+// online and has requested history with the strategy. Containers are removed when backend is closed.
+func churnWithHistory(t *testing.T, strategy Strategy) (*fakebackend.Backend, *ChurnSim, []*cluster.Client) {
+	backend := fakebackend.New()
+	ipam, err := cluster.NewIPAM("10.0.0.0/24")
+	require.NoError(t, err)
+	c := cluster.NewCluster("test", ipam, backend, "statusd", "client", "bootnode", "rendezvous", false)
+	require.NoError(t, c.Create(context.TODO(), cluster.ScaleOpts{Users: 2, Deploy: true}))
+
+	users := c.GetUsers()
+	chat := gethservice.Contact{Name: "chat"}
+	for _, u := range users {
+		require.NoError(t, client.ChatClient(u.Rpc()).AddContact(context.TODO(), chat))
+	}
+	// first user is always online, second is stopped at 50ms and started at 2s. margins are wide,
+	// so that the message is sent while the user is stopped even on a slow machine
+	sim := NewChurnSim([]cluster.Node{users[0], users[1]}, Params{
+		Modes: []Mode{Stop},
+		Model: Trace{1: {{Start: 0, End: 50 * time.Millisecond}, {Start: 2 * time.Second, End: time.Hour}}},
+		History: History{
+			Strategy: strategy,
+			Contacts: map[string][]gethservice.Contact{users[1].UID(): {chat}},
+		},
+	})
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		done <- sim.Run(ctx)
+	}()
+	waitFor := func(msg string, cond func() bool) {
+		deadline := time.Now().Add(10 * time.Second)
+		for !cond() {
+			require.True(t, time.Now().Before(deadline), msg)
+			time.Sleep(10 * time.Millisecond)
+		}
+	}
+	waitFor("user wasn't stopped", func() bool {
+		state, _ := backend.State(users[1].UID())
+		return state == fakebackend.Stopped
+	})
+	require.NoError(t, client.ChatClient(users[0].Rpc()).Send(context.TODO(), chat, "missed"))
+	state, _ := backend.State(users[1].UID())
+	require.Equal(t, fakebackend.Stopped, state, "message must be sent while the user is stopped")
+	waitFor("user wasn't started", func() bool {
+		return len(sim.Transitions()) == 2
+	})
+	// inbox is read before and after the history request
+	waitFor("history wasn't requested", func() bool {
+		return len(backend.Calls("ReadContactMessages")) == 2
+	})
+	require.NoError(t, client.ChatClient(users[0].Rpc()).Send(context.TODO(), chat, "live"))
+	cancel()
+	require.NoError(t, <-done)
+	return backend, sim, users
+}
+
+func TestChurnWithFakeBackend(t *testing.T) {
+	backend, sim, users := churnWithHistory(t, RequestAll)
+	defer backend.Close()
+
+	transitions := sim.Transitions()
+	require.Len(t, transitions, 2)
+	for i, online := range []bool{false, true} {
+		require.Equal(t, users[1].UID(), transitions[i].Peer)
+		require.Equal(t, online, transitions[i].Online)
+		require.NoError(t, transitions[i].Err)
+	}
+	require.Len(t, backend.Calls("Stop", "Start"), 2)
+	require.Len(t, backend.Requests(users[1].UID()), 1)
+
+	recoveries := sim.Recoveries()
+	require.Len(t, recoveries, 1)
+	require.Equal(t, 1, recoveries[0].Recovered)
+	require.Equal(t, 1, recoveries[0].Live)
+}
+
+func TestChurnWithoutHistory(t *testing.T) {
+	backend, sim, users := churnWithHistory(t, RequestNone)
+	defer backend.Close()
+
+	require.Empty(t, backend.Requests(users[1].UID()))
+	recoveries := sim.Recoveries()
+	require.Len(t, recoveries, 1)
+	require.Equal(t, 0, recoveries[0].Recovered)
+	require.Equal(t, 1, recoveries[0].Live)
+}
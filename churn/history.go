@@ -0,0 +1,218 @@
+package churn
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/status-im/status-console-client/protocol/gethservice"
+
+	"github.com/status-im/status-scale/client"
+	"github.com/status-im/status-scale/cluster"
+	"github.com/status-im/status-scale/utils"
+)
+
+// Strategy defines which messages user requests from mail servers when it is back online.
+type Strategy string
+
+const (
+	// RequestAll requests messages for all chats of the user. This is the default.
+	RequestAll Strategy = "all"
+	// RequestTargeted requests messages for every contact since the user went offline, minus margin.
+	RequestTargeted Strategy = "targeted"
+	// RequestNone relies only on live delivery.
+	RequestNone Strategy = "none"
+
+	// DefaultMargin covers envelopes that were in flight when user went offline.
+	DefaultMargin = 10 * time.Second
+
+	requestLimit = 1000
+)
+
+// Strategies are all supported strategies.
+var Strategies = []Strategy{RequestAll, RequestTargeted, RequestNone}
+
+// History configures requests for messages that were missed while user was offline.
+type History struct {
+	Strategy Strategy
+	Margin   time.Duration
+	// Contacts of users by uid. Targeted requests are sent for every contact, and messages in chats with
+	// contacts are counted after every offline period.
+	Contacts map[string][]gethservice.Contact
+}
+
+// Recovery counts messages that user received after it was back online, until it went offline again
+// or simulation was finished.
+type Recovery struct {
+	Peer    string
+	Offline time.Time
+	Online  time.Time
+	// Recovered messages arrived while the history request was processed, Live messages arrived at any other time
+	// after the user was back online. Messages that were re-broadcasted during the request are counted as recovered.
+	Recovered int
+	Live      int
+	// RequestErr is set if history request failed after user was back online.
+	RequestErr error
+}
+
+// messageKey identifies message, ids are not exposed over rpc.
+type messageKey struct {
+	clock     int64
+	timestamp int64
+	text      string
+}
+
+// inbox tracks messages of a user between offline periods.
+type inbox struct {
+	lastSeen time.Time
+	online   time.Time
+	known    map[messageKey]bool
+	// recovery is open until messages of the online period are counted
+	recovery *Recovery
+}
+
+func (c *ChurnSim) inbox(i int) *inbox {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.inboxes == nil {
+		c.inboxes = map[int]*inbox{}
+	}
+	box, exist := c.inboxes[i]
+	if !exist {
+		box = &inbox{}
+		c.inboxes[i] = box
+	}
+	return box
+}
+
+// Recoveries returns counts of recovered and live messages after every offline period of users with contacts.
+func (c *ChurnSim) Recoveries() []Recovery {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]Recovery{}, c.recoveries...)
+}
+
+// history requests messages that the user missed while it was offline. Failure doesn't exclude the user
+// from the simulation, it is logged and reported with the recovery.
+// Messages of the previous online period are counted here, after the transition, so that stop is not delayed.
+func (c *ChurnSim) history(ctx context.Context, i int) {
+	user, ok := c.participants[i].(*cluster.Client)
+	if !ok {
+		return
+	}
+	box := c.inbox(i)
+	tracked := len(c.Params.History.Contacts[user.UID()]) != 0 && c.snapshot(ctx, i, user)
+	log.Debug("trying to fetch messages from mail server", "peer", user.UID(), "strategy", c.Params.History.Strategy)
+	err := c.request(ctx, i, user)
+	if err != nil {
+		log.Error("failed to request messages from mail server", "peer", user.UID(), "error", err)
+	} else {
+		log.Debug("fetched messages from mail server", "peer", user.UID())
+	}
+	if !tracked {
+		return
+	}
+	// messages that arrived between snapshots before and after the request are recovered
+	messages, rerr := c.read(ctx, user)
+	if rerr != nil {
+		log.Error("failed to count messages", "peer", user.UID(), "error", rerr)
+		return
+	}
+	box.recovery = &Recovery{
+		Peer:       user.UID(),
+		Offline:    box.lastSeen,
+		Online:     box.online,
+		Recovered:  unknown(messages, box.known),
+		RequestErr: err,
+	}
+	box.known = messages
+}
+
+// request requests messages according to the strategy.
+func (c *ChurnSim) request(ctx context.Context, i int, user *cluster.Client) error {
+	chat := client.ChatClient(user.Rpc())
+	switch c.Params.History.Strategy {
+	case RequestNone:
+		return nil
+	case RequestTargeted:
+		margin := c.Params.History.Margin
+		if margin == 0 {
+			margin = DefaultMargin
+		}
+		from := c.inbox(i).lastSeen.Add(-margin).Unix()
+		for _, contact := range c.Params.History.Contacts[user.UID()] {
+			params := client.RequestParams{Contact: contact, Limit: requestLimit, From: from, To: time.Now().Unix()}
+			err := poll(ctx, func(ctx context.Context) error {
+				return chat.Request(ctx, params)
+			})
+			if err != nil {
+				return err
+			}
+		}
+		log.Debug("requested messages for contacts", "peer", user.UID(), "from", from)
+		return nil
+	default:
+		return poll(ctx, chat.RequestAll)
+	}
+}
+
+func poll(ctx context.Context, request func(context.Context) error) error {
+	return utils.PollImmediateNoError(ctx, func(parent context.Context) error {
+		ctx, cancel := context.WithTimeout(parent, 5*time.Second)
+		defer cancel()
+		if err := request(ctx); err != nil {
+			return fmt.Errorf("requesting messages failed: %v", err)
+		}
+		return nil
+	}, 2*time.Second, 30*time.Second)
+}
+
+// read returns messages in chats with contacts of the user.
+func (c *ChurnSim) read(ctx context.Context, user *cluster.Client) (map[messageKey]bool, error) {
+	chat := client.ChatClient(user.Rpc())
+	rst := map[messageKey]bool{}
+	for _, contact := range c.Params.History.Contacts[user.UID()] {
+		msgs, err := chat.Messages(ctx, contact, 0)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read messages of %s: %v", user.UID(), err)
+		}
+		for _, msg := range msgs {
+			rst[messageKey{clock: msg.Clock, timestamp: int64(msg.Timestamp), text: msg.Text}] = true
+		}
+	}
+	return rst, nil
+}
+
+// snapshot reads messages of the user. Messages that arrived since the previous snapshot are counted as live
+// in the open recovery, which is recorded. Returns false if messages can't be read.
+func (c *ChurnSim) snapshot(ctx context.Context, i int, user *cluster.Client) bool {
+	if len(c.Params.History.Contacts[user.UID()]) == 0 {
+		return false
+	}
+	box := c.inbox(i)
+	messages, err := c.read(ctx, user)
+	if err != nil {
+		log.Error("failed to count messages", "peer", user.UID(), "error", err)
+		return false
+	}
+	if rec := box.recovery; rec != nil {
+		rec.Live = unknown(messages, box.known)
+		c.mu.Lock()
+		c.recoveries = append(c.recoveries, *rec)
+		c.mu.Unlock()
+		box.recovery = nil
+	}
+	box.known = messages
+	return true
+}
+
+// unknown counts messages that are not in known.
+func unknown(messages, known map[messageKey]bool) (count int) {
+	for key := range messages {
+		if !known[key] {
+			count++
+		}
+	}
+	return count
+}
@@ -0,0 +1,210 @@
+package churn
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// Forever is returned by a model if participant stays in the current state until the end of simulation.
+const Forever = time.Duration(math.MaxInt64)
+
+// SessionModel generates online and offline periods for participants. Elapsed is a scheduled time
+// of the period since the start of simulation. Models are safe for concurrent use by different participants,
+// and for a given seed produce the same sequence of periods for every participant.
+type SessionModel interface {
+	// Online returns duration of the online session of i-th participant.
+	Online(i int, elapsed time.Duration) time.Duration
+	// Offline returns duration of the offline period of i-th participant.
+	Offline(i int, elapsed time.Duration) time.Duration
+}
+
+// sources keeps a random source per participant, so that periods of a participant don't depend on
+// the order in which other participants request them.
+type sources struct {
+	seed int64
+
+	mu   sync.Mutex
+	rngs map[int]*rand.Rand
+}
+
+func (s *sources) get(i int) *rand.Rand {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.rngs == nil {
+		s.rngs = map[int]*rand.Rand{}
+	}
+	rng, exist := s.rngs[i]
+	if !exist {
+		rng = rand.New(rand.NewSource(s.seed + int64(i)))
+		s.rngs[i] = rng
+	}
+	return rng
+}
+
+// Distribution samples durations of periods.
+type Distribution interface {
+	Sample(*rand.Rand) time.Duration
+	Validate() error
+}
+
+// Exponential distribution is memoryless, sessions end at a constant rate.
+type Exponential struct {
+	Mean time.Duration
+}
+
+func (e Exponential) Sample(rng *rand.Rand) time.Duration {
+	return time.Duration(rng.ExpFloat64() * float64(e.Mean))
+}
+
+func (e Exponential) Validate() error {
+	if e.Mean <= 0 {
+		return errors.New("exponential mean must be positive")
+	}
+	return nil
+}
+
+// Weibull with shape below 1 produces many short and a few very long sessions.
+type Weibull struct {
+	Shape float64
+	Scale time.Duration
+}
+
+func (w Weibull) Sample(rng *rand.Rand) time.Duration {
+	return time.Duration(float64(w.Scale) * math.Pow(-math.Log(1-rng.Float64()), 1/w.Shape))
+}
+
+func (w Weibull) Validate() error {
+	if w.Shape <= 0 || w.Scale <= 0 {
+		return errors.New("weibull shape and scale must be positive")
+	}
+	return nil
+}
+
+// Pareto is a heavy tailed distribution, Scale is the minimal duration.
+type Pareto struct {
+	Shape float64
+	Scale time.Duration
+}
+
+func (p Pareto) Sample(rng *rand.Rand) time.Duration {
+	return time.Duration(float64(p.Scale) / math.Pow(1-rng.Float64(), 1/p.Shape))
+}
+
+func (p Pareto) Validate() error {
+	if p.Shape <= 0 || p.Scale <= 0 {
+		return errors.New("pareto shape and scale must be positive")
+	}
+	return nil
+}
+
+// NewUniform returns a model with fixed online sessions and offline periods uniformly distributed
+// in [jitter, 3*jitter). First session is shortened randomly, so that participants don't go offline at once.
+func NewUniform(live, jitter time.Duration, seed int64) SessionModel {
+	return &uniform{live: live, jitter: jitter, sources: sources{seed: seed}}
+}
+
+type uniform struct {
+	live, jitter time.Duration
+	sources
+}
+
+func (u *uniform) Online(i int, elapsed time.Duration) time.Duration {
+	if elapsed == 0 {
+		return random(u.get(i), u.live)
+	}
+	return u.live
+}
+
+func (u *uniform) Offline(i int, elapsed time.Duration) time.Duration {
+	return u.jitter + random(u.get(i), 2*u.jitter)
+}
+
+// random returns uniformly distributed duration in [0, d).
+func random(rng *rand.Rand, d time.Duration) time.Duration {
+	if d <= 0 {
+		return 0
+	}
+	return time.Duration(rng.Int63n(int64(d)))
+}
+
+// NewSessions returns a model with online and offline periods sampled independently from distributions.
+func NewSessions(online, offline Distribution, seed int64) (SessionModel, error) {
+	if err := online.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid online distribution: %v", err)
+	}
+	if err := offline.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid offline distribution: %v", err)
+	}
+	return &sessions{online: online, offline: offline, sources: sources{seed: seed}}, nil
+}
+
+type sessions struct {
+	online, offline Distribution
+	sources
+}
+
+func (s *sessions) Online(i int, elapsed time.Duration) time.Duration {
+	return s.online.Sample(s.get(i))
+}
+
+func (s *sessions) Offline(i int, elapsed time.Duration) time.Duration {
+	return s.offline.Sample(s.get(i))
+}
+
+// Diurnal changes fraction of online participants during a simulated day, from Min at night to Max at Peak.
+// Online sessions are exponential with mean Session, offline periods are exponential with mean that
+// keeps online fraction of the current time of the day.
+type Diurnal struct {
+	// Day is a duration of the simulated day, e.g. 1h.
+	Day time.Duration
+	// Peak is an offset from the start of the simulation with the highest online fraction.
+	Peak     time.Duration
+	Min, Max float64
+	Session  time.Duration
+}
+
+func (d Diurnal) Validate() error {
+	if d.Day <= 0 || d.Session <= 0 {
+		return errors.New("diurnal day and session must be positive")
+	}
+	if d.Min <= 0 || d.Min > d.Max || d.Max > 1 {
+		return fmt.Errorf("diurnal online fractions must be 0 < min <= max <= 1, got %v and %v", d.Min, d.Max)
+	}
+	return nil
+}
+
+// Fraction returns expected online fraction at elapsed time.
+func (d Diurnal) Fraction(elapsed time.Duration) float64 {
+	phase := 2 * math.Pi * float64(elapsed-d.Peak) / float64(d.Day)
+	return d.Min + (d.Max-d.Min)*(1+math.Cos(phase))/2
+}
+
+// NewDiurnal returns diurnal model.
+func NewDiurnal(d Diurnal, seed int64) (SessionModel, error) {
+	if err := d.Validate(); err != nil {
+		return nil, err
+	}
+	return &diurnal{Diurnal: d, sources: sources{seed: seed}}, nil
+}
+
+type diurnal struct {
+	Diurnal
+	sources
+}
+
+func (d *diurnal) Online(i int, elapsed time.Duration) time.Duration {
+	return Exponential{Mean: d.Session}.Sample(d.get(i))
+}
+
+func (d *diurnal) Offline(i int, elapsed time.Duration) time.Duration {
+	p := d.Fraction(elapsed)
+	mean := time.Duration(float64(d.Session) * (1 - p) / p)
+	if mean == 0 {
+		return 0
+	}
+	return Exponential{Mean: mean}.Sample(d.get(i))
+}
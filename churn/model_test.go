@@ -0,0 +1,67 @@
+package churn
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestSessionsSeed(t *testing.T) {
+	online := Weibull{Shape: 0.5, Scale: time.Minute}
+	offline := Pareto{Shape: 1.5, Scale: 10 * time.Second}
+	first, err := NewSessions(online, offline, 7)
+	require.NoError(t, err)
+	second, err := NewSessions(online, offline, 7)
+	require.NoError(t, err)
+	// periods of a participant don't depend on other participants
+	expected := []time.Duration{first.Online(0, 0), first.Offline(0, 0), first.Online(0, 0)}
+	second.Online(1, 0)
+	second.Online(1, 0)
+	require.Equal(t, expected, []time.Duration{second.Online(0, 0), second.Offline(0, 0), second.Online(0, 0)})
+	require.NotEqual(t, expected[0], second.Online(1, 0))
+
+	_, err = NewSessions(Exponential{}, offline, 7)
+	require.Error(t, err)
+}
+
+func TestDistributions(t *testing.T) {
+	model, err := NewSessions(Exponential{Mean: time.Minute}, Pareto{Shape: 2, Scale: time.Second}, 1)
+	require.NoError(t, err)
+	var total time.Duration
+	for i := 0; i < 10000; i++ {
+		total += model.Online(0, 0)
+		require.True(t, model.Offline(0, 0) >= time.Second)
+	}
+	require.InDelta(t, float64(time.Minute), float64(total/10000), float64(5*time.Second))
+}
+
+func TestDiurnalFraction(t *testing.T) {
+	d := Diurnal{Day: time.Hour, Peak: 15 * time.Minute, Min: 0.2, Max: 0.8, Session: time.Minute}
+	require.NoError(t, d.Validate())
+	require.InDelta(t, 0.8, d.Fraction(15*time.Minute), 1e-9)
+	require.InDelta(t, 0.2, d.Fraction(45*time.Minute), 1e-9)
+	require.InDelta(t, 0.8, d.Fraction(75*time.Minute), 1e-9)
+	require.Error(t, Diurnal{Day: time.Hour, Min: 0, Max: 0.5, Session: time.Minute}.Validate())
+}
+
+func TestTrace(t *testing.T) {
+	trace, err := ReadTrace(strings.NewReader(`
+# participant, start, end
+0, 10, 20
+0, 0, 5
+1, 0, 1.5
+`))
+	require.NoError(t, err)
+	require.Equal(t, []Session{{0, 5 * time.Second}, {10 * time.Second, 20 * time.Second}}, trace[0])
+
+	require.Equal(t, 5*time.Second, trace.Online(0, 0))
+	require.Equal(t, 5*time.Second, trace.Offline(0, 5*time.Second))
+	require.Equal(t, 10*time.Second, trace.Online(0, 10*time.Second))
+	require.Equal(t, Forever, trace.Offline(0, 20*time.Second))
+	require.Equal(t, Forever, trace.Online(2, 0))
+
+	_, err = ReadTrace(strings.NewReader("0,0,10\n0,5,15\n"))
+	require.Error(t, err)
+}
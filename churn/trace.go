@@ -0,0 +1,99 @@
+package churn
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Session is an online period since the start of simulation.
+type Session struct {
+	Start, End time.Duration
+}
+
+// Trace replays recorded online sessions of participants. Participants are offline between sessions and after
+// the last session. Participants without sessions in the trace are always online.
+type Trace map[int][]Session
+
+// ReadTrace reads csv with participant index, start and end of online session in seconds in every row,
+// e.g. 0,10,75.5. Sessions of a participant must not overlap.
+func ReadTrace(r io.Reader) (Trace, error) {
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = 3
+	reader.TrimLeadingSpace = true
+	trace := Trace{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		i, err := strconv.Atoi(strings.TrimSpace(record[0]))
+		if err != nil || i < 0 {
+			return nil, fmt.Errorf("invalid participant %s", record[0])
+		}
+		var bounds [2]time.Duration
+		for j := range bounds {
+			value, err := strconv.ParseFloat(strings.TrimSpace(record[j+1]), 64)
+			if err != nil {
+				return nil, fmt.Errorf("invalid time %s: %v", record[j+1], err)
+			}
+			bounds[j] = time.Duration(value * float64(time.Second))
+		}
+		if bounds[0] < 0 || bounds[1] <= bounds[0] {
+			return nil, fmt.Errorf("invalid session of participant %d: %v-%v", i, bounds[0], bounds[1])
+		}
+		trace[i] = append(trace[i], Session{Start: bounds[0], End: bounds[1]})
+	}
+	for i, sessions := range trace {
+		sort.Slice(sessions, func(a, b int) bool {
+			return sessions[a].Start < sessions[b].Start
+		})
+		for j := 1; j < len(sessions); j++ {
+			if sessions[j].Start < sessions[j-1].End {
+				return nil, fmt.Errorf("sessions of participant %d overlap at %v", i, sessions[j].Start)
+			}
+		}
+	}
+	return trace, nil
+}
+
+// LoadTrace reads trace from a csv file.
+func LoadTrace(path string) (Trace, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadTrace(f)
+}
+
+func (t Trace) Online(i int, elapsed time.Duration) time.Duration {
+	sessions, exist := t[i]
+	if !exist {
+		return Forever
+	}
+	for _, s := range sessions {
+		if elapsed >= s.Start && elapsed < s.End {
+			return s.End - elapsed
+		}
+	}
+	return 0
+}
+
+func (t Trace) Offline(i int, elapsed time.Duration) time.Duration {
+	for _, s := range t[i] {
+		if s.Start >= elapsed {
+			return s.Start - elapsed
+		}
+	}
+	return Forever
+}
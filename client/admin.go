@@ -22,3 +22,11 @@ func (a Admin) Self(ctx context.Context) (rst *p2p.NodeInfo, err error) {
 func (a Admin) Peers(ctx context.Context) (rst []*p2p.PeerInfo, err error) {
 	return rst, a.client.CallContext(ctx, &rst, "admin_peers")
 }
+
+func (a Admin) AddPeer(ctx context.Context, enode string) (rst bool, err error) {
+	return rst, a.client.CallContext(ctx, &rst, "admin_addPeer", enode)
+}
+
+func (a Admin) RemovePeer(ctx context.Context, enode string) (rst bool, err error) {
+	return rst, a.client.CallContext(ctx, &rst, "admin_removePeer", enode)
+}
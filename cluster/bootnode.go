@@ -6,6 +6,7 @@ import (
 	"encoding/hex"
 	"fmt"
 	"net"
+	"strconv"
 	"strings"
 
 	"github.com/ethereum/go-ethereum/crypto"
@@ -23,6 +24,8 @@ type BootnodeConfig struct {
 	Network string
 	Enodes  []string
 	Image   string
+	// Emulator applies network conditions, netem is used if nil.
+	Emulator network.Emulator `json:"-"`
 }
 
 func NewBootnode(cfg BootnodeConfig, backend Backend) Bootnode {
@@ -39,6 +42,9 @@ func NewBootnode(cfg BootnodeConfig, backend Backend) Bootnode {
 		key:     key,
 		enodes:  cfg.Enodes,
 		image:   cfg.Image,
+
+		emulator:   cfg.Emulator,
+		conditions: newConditions(cfg.Emulator),
 	}
 }
 
@@ -54,24 +60,48 @@ type Bootnode struct {
 
 	backend Backend
 	key     *ecdsa.PrivateKey
+
+	emulator network.Emulator
+	// conditions are shared by copies of the bootnode
+	conditions *conditions
+}
+
+func (b Bootnode) config() *BootnodeConfig {
+	return &BootnodeConfig{
+		Name:    b.name,
+		IP:      b.ip,
+		Network: b.network,
+		Enodes:  b.enodes,
+		Image:   b.image,
+
+		Emulator: b.emulator,
+	}
 }
 
 func (b Bootnode) IP() string {
 	return b.ip
 }
 
+func (b Bootnode) UID() string {
+	return b.name
+}
+
+func (b Bootnode) Type() PeerType {
+	return Boot
+}
+
 func (b Bootnode) String() string {
 	return fmt.Sprintf("bootnode %s %s", b.name, b.ip)
 }
 
 func (b Bootnode) Create(ctx context.Context) error {
 	data := hex.EncodeToString(crypto.FromECDSA(b.key))
-	cmd := []string{"-addr=" + fmt.Sprintf("%s:%d", b.ip, b.port), "-keydata=" + data}
+	cmd := []string{"-addr=" + net.JoinHostPort(b.ip, strconv.Itoa(b.port)), "-keydata=" + data}
 	for _, e := range b.enodes {
 		cmd = append(cmd, "-n="+e)
 	}
 	log.Debug("creating bootnode", "name", b.name, "enode", b.Self().String(), "cmd", strings.Join(cmd, " "))
-	return b.backend.Create(ctx, b.name, dockershim.CreateOpts{
+	err := b.backend.Create(ctx, b.name, dockershim.CreateOpts{
 		Entrypoint: "bootnode",
 		Cmd:        cmd,
 		Image:      b.image,
@@ -81,6 +111,10 @@ func (b Bootnode) Create(ctx context.Context) error {
 		}},
 	},
 	)
+	if err != nil {
+		return err
+	}
+	return b.conditions.reapply(ctx, b.shell)
 }
 
 func (b Bootnode) Self() *discv5.Node {
@@ -94,19 +128,56 @@ func (b Bootnode) Remove(ctx context.Context) error {
 	return b.backend.Remove(ctx, b.name)
 }
 
-func (b Bootnode) EnableConditions(ctx context.Context, opt network.Options) error {
-	return network.ComcastStart(func(ctx context.Context, cmd []string) error {
-		return b.backend.Execute(ctx, b.name, cmd)
-	}, ctx, opt)
+func (b Bootnode) shell(ctx context.Context, cmd []string) error {
+	return b.backend.Execute(ctx, b.name, cmd)
+}
+
+func (b Bootnode) EnableConditions(ctx context.Context, opts ...network.Options) error {
+	return b.conditions.enable(ctx, b.shell, opts...)
 }
 
-func (b Bootnode) DisableConditions(ctx context.Context, opt network.Options) error {
-	return network.ComcastStop(func(ctx context.Context, cmd []string) error {
-		return b.backend.Execute(ctx, b.name, cmd)
-	}, ctx, opt)
+func (b Bootnode) DisableConditions(ctx context.Context, opts ...network.Options) error {
+	return b.conditions.disable(ctx, b.shell, opts...)
+}
+
+// ActiveConditions returns network conditions that are enabled on the bootnode.
+func (b Bootnode) ActiveConditions() []network.Options {
+	return b.conditions.list()
 }
 
 func (b Bootnode) Reboot(ctx context.Context) error {
 	log.Debug("reboot", "bootnode", b.name)
-	return b.backend.Reboot(ctx, b.name)
+	if err := b.backend.Reboot(ctx, b.name); err != nil {
+		return err
+	}
+	return b.conditions.reapply(ctx, b.shell)
+}
+
+func (b Bootnode) Pause(ctx context.Context) error {
+	log.Debug("pause", "bootnode", b.name)
+	return b.backend.Pause(ctx, b.name)
+}
+
+func (b Bootnode) Unpause(ctx context.Context) error {
+	log.Debug("unpause", "bootnode", b.name)
+	return b.backend.Unpause(ctx, b.name)
+}
+
+func (b Bootnode) Stop(ctx context.Context) error {
+	log.Debug("stop", "bootnode", b.name)
+	return b.backend.Stop(ctx, b.name)
+}
+
+func (b Bootnode) Kill(ctx context.Context) error {
+	log.Debug("kill", "bootnode", b.name)
+	return b.backend.Kill(ctx, b.name)
+}
+
+// Start starts the bootnode after Stop or Kill and restores its network conditions.
+func (b Bootnode) Start(ctx context.Context) error {
+	log.Debug("start", "bootnode", b.name)
+	if err := b.backend.Start(ctx, b.name); err != nil {
+		return err
+	}
+	return b.conditions.reapply(ctx, b.shell)
 }
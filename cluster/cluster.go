@@ -3,6 +3,9 @@ package cluster
 import (
 	"context"
 	"fmt"
+	"math"
+	"net"
+	"os"
 	"strconv"
 	"strings"
 	"sync"
@@ -15,40 +18,80 @@ import (
 	"github.com/status-im/status-scale/utils"
 )
 
-// FIXME(dshulyak) implement single Peer interface
-type Creatable interface {
+// Node is implemented by every container that is managed by the cluster.
+type Node interface {
 	Create(context.Context) error
-}
-
-type Removable interface {
 	Remove(context.Context) error
-}
-
-type Rebootable interface {
 	Reboot(context.Context) error
-}
-
-type Enforsable interface {
+	// Pause and Unpause freeze and unfreeze all processes of the node.
+	Pause(context.Context) error
+	Unpause(context.Context) error
+	// Stop and Kill stop the node gracefully or immediately, Start starts it again with the same data.
+	Stop(context.Context) error
+	Kill(context.Context) error
+	Start(context.Context) error
 	EnableConditions(ctx context.Context, opts ...network.Options) error
 	DisableConditions(ctx context.Context, opts ...network.Options) error
-}
-
-type AssignedIP interface {
+	ActiveConditions() []network.Options
 	IP() string
+	UID() string
+	String() string
+	Type() PeerType
 }
 
 type PeerType string
 
 type ScaleOpts struct {
-	Boot            int
-	Relay           int
-	Users           int
-	MVDS            int
-	Rendezvous      int
-	Mails           int
-	Deploy          bool
+	Boot       int
+	Relay      int
+	Users      int
+	MVDS       int
+	Rendezvous int
+	Mails      int
+	Deploy     bool
+	// NoDiscovery disables discovery for status-go peers. Such peers must be connected explicitly.
+	NoDiscovery     bool
 	Enodes          []string
 	RendezvousNodes []string
+	// Images overwrite default cluster images for peers of a type.
+	Images map[PeerType]Image
+	// Templates change configs of status-go peers of a type, after DefaultTemplates.
+	Templates map[PeerType]Template
+	// NAT puts users and mvds peers behind nat gateways.
+	NAT *NATOpts
+}
+
+// Image is used for a fraction of peers in a batch. If Ratio is zero image is used for all peers.
+type Image struct {
+	Name  string  `json:"name" yaml:"name"`
+	Ratio float64 `json:"ratio" yaml:"ratio"`
+}
+
+// image returns image for i-th peer of the type in the batch.
+// First peers in the batch get an overwritten image.
+func (opts ScaleOpts) image(typ PeerType, i int, def string) string {
+	img, exist := opts.Images[typ]
+	if !exist || len(img.Name) == 0 {
+		return def
+	}
+	if img.Ratio == 0 {
+		return img.Name
+	}
+	if float64(i) < math.Round(float64(opts.counts()[typ])*img.Ratio) {
+		return img.Name
+	}
+	return def
+}
+
+func (opts ScaleOpts) counts() map[PeerType]int {
+	return map[PeerType]int{
+		Boot:           opts.Boot,
+		Relay:          opts.Relay,
+		User:           opts.Users,
+		MVDS:           opts.MVDS,
+		RendezvousBoot: opts.Rendezvous,
+		Mail:           opts.Mails,
+	}
 }
 
 const (
@@ -58,10 +101,14 @@ const (
 	User           PeerType = "user"
 	MVDS           PeerType = "mvds"
 	RendezvousBoot PeerType = "rendezvous"
+	NATGateway     PeerType = "gateway"
 )
 
-func NewCluster(pref string, ipam *IPAM, b Backend, statusd, client, bootnode, rendezvous string, keep bool) Cluster {
-	c := Cluster{
+// AllTypes in the order in which they are deployed.
+var AllTypes = []PeerType{Boot, RendezvousBoot, Mail, Relay, NATGateway, User, MVDS}
+
+func NewCluster(pref string, ipam *IPAM, b Backend, statusd, client, bootnode, rendezvous string, keep bool) *Cluster {
+	c := &Cluster{
 		Prefix:         pref,
 		IPAM:           ipam,
 		Backend:        b,
@@ -71,8 +118,9 @@ func NewCluster(pref string, ipam *IPAM, b Backend, statusd, client, bootnode, r
 		RendezvousBoot: rendezvous,
 		Keep:           keep,
 
-		pending: map[PeerType][]interface{}{},
-		running: map[PeerType][]interface{}{},
+		pending: map[PeerType][]Node{},
+		running: map[PeerType][]Node{},
+		created: map[PeerType]int{},
 	}
 	return c
 }
@@ -90,11 +138,26 @@ type Cluster struct {
 
 	// dont remove cluster after tests are finished
 	Keep bool
+	// Emulator applies network conditions to every node, netem is used if nil.
+	Emulator network.Emulator
+	// NATSubnets are split into /24 private networks for nat gateways. DefaultNATSubnets are used if empty.
+	NATSubnets string
+	// RunDir is a directory where state of the cluster is persisted.
+	// If not empty cluster with the same prefix can be reattached by another process.
+	RunDir string
 
 	mu      sync.Mutex
 	netID   string
-	pending map[PeerType][]interface{}
-	running map[PeerType][]interface{}
+	pending map[PeerType][]Node
+	running map[PeerType][]Node
+	// created is used to generate unique names, it is never decremented
+	created map[PeerType]int
+	// attached counts peers that were attached and weren't requested by create yet
+	attached map[PeerType]int
+	// partition is a set of rules that are applied by Partition
+	partition []rule
+	// regions is a set of rules that are applied by ApplyRegions
+	regions []rule
 }
 
 func (c *Cluster) getName(parts ...string) string {
@@ -103,15 +166,65 @@ func (c *Cluster) getName(parts ...string) string {
 	return strings.Join(fqn, "_")
 }
 
+// Create adds peers to the cluster. Peers that were attached are reused by the first calls,
+// so that a test or scenario creates only the peers that are missing in a kept cluster.
 func (c *Cluster) Create(ctx context.Context, opts ScaleOpts) error {
-	return c.create(ctx, opts)
+	return c.create(ctx, c.reuseAttached(opts))
 }
 
-func (c *Cluster) totalOfType(typ PeerType) int {
-	return len(c.pending[typ]) + len(c.running[typ])
+// Attached is true if the cluster was attached to containers of a previous run.
+func (c *Cluster) Attached() bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.attached != nil
+}
+
+// reuseAttached subtracts attached peers from the requested counts.
+func (c *Cluster) reuseAttached(opts ScaleOpts) ScaleOpts {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.attached == nil {
+		return opts
+	}
+	for _, count := range []struct {
+		typ PeerType
+		n   *int
+	}{
+		{Boot, &opts.Boot}, {RendezvousBoot, &opts.Rendezvous}, {Mail, &opts.Mails},
+		{Relay, &opts.Relay}, {User, &opts.Users}, {MVDS, &opts.MVDS},
+	} {
+		reused := c.attached[count.typ]
+		if reused > *count.n {
+			reused = *count.n
+		}
+		*count.n -= reused
+		c.attached[count.typ] -= reused
+	}
+	return opts
 }
 
 func (c *Cluster) create(ctx context.Context, opts ScaleOpts) error {
+	if err := c.build(ctx, opts); err != nil {
+		return err
+	}
+	if opts.Deploy {
+		return c.DeployPending(ctx)
+	}
+	return nil
+}
+
+// build adds peers from opts to pending. If any peer can't be built the whole batch is dropped,
+// so that names and addresses are not leaked.
+func (c *Cluster) build(ctx context.Context, opts ScaleOpts) (err error) {
+	pending := map[PeerType]int{}
+	for typ, nodes := range c.pending {
+		pending[typ] = len(nodes)
+	}
+	defer func() {
+		if err != nil {
+			c.dropPending(ctx, pending)
+		}
+	}()
 	log.Debug(
 		"Adding nodes to cluster.", "name", c.Prefix, "cidr", c.IPAM,
 		"boot count", opts.Boot, "relay count", opts.Relay, "users count", opts.Users, "mvds count", opts.MVDS, "mailservers count", opts.Mails,
@@ -143,19 +256,24 @@ func (c *Cluster) create(ctx context.Context, opts ScaleOpts) error {
 	}
 	// FIXME(dshulyak) there is definitely reusable pattern.
 	// note that bootnodes and mail servers have to be created before and passed to relays/users
-	boot := c.totalOfType(Boot)
-	relay := c.totalOfType(Relay)
-	users := c.totalOfType(User)
-	mails := c.totalOfType(Mail)
-	mvds := c.totalOfType(MVDS)
-	rendezvous := c.totalOfType(RendezvousBoot)
+	boot := c.created[Boot]
+	relay := c.created[Relay]
+	users := c.created[User]
+	mails := c.created[Mail]
+	mvds := c.created[MVDS]
+	rendezvous := c.created[RendezvousBoot]
 	for i := boot; i < boot+opts.Boot; i++ {
+		ip, err := c.IPAM.Take()
+		if err != nil {
+			return err
+		}
 		b := NewBootnode(BootnodeConfig{
-			Name:    c.getName(string(Boot), strconv.Itoa(i)),
-			Network: netID,
-			IP:      c.IPAM.Take().String(),
-			Enodes:  enodes,
-			Image:   c.Bootnode,
+			Name:     c.getName(string(Boot), strconv.Itoa(i)),
+			Network:  netID,
+			IP:       ip.String(),
+			Enodes:   enodes,
+			Image:    opts.image(Boot, i-boot, c.Bootnode),
+			Emulator: c.Emulator,
 		}, c.Backend)
 		c.pending[Boot] = append(c.pending[Boot], b)
 		if opts.Enodes == nil {
@@ -169,12 +287,17 @@ func (c *Cluster) create(ctx context.Context, opts ScaleOpts) error {
 		}
 	}
 	for i := rendezvous; i < rendezvous+opts.Rendezvous; i++ {
-		r := Rendezvous(NewBootnode(BootnodeConfig{
-			Name:    c.getName(string(RendezvousBoot), strconv.Itoa(i)),
-			Network: netID,
-			IP:      c.IPAM.Take().String(),
-			Image:   c.RendezvousBoot,
-		}, c.Backend))
+		ip, err := c.IPAM.Take()
+		if err != nil {
+			return err
+		}
+		r := NewRendezvous(BootnodeConfig{
+			Name:     c.getName(string(RendezvousBoot), strconv.Itoa(i)),
+			Network:  netID,
+			IP:       ip.String(),
+			Image:    opts.image(RendezvousBoot, i-rendezvous, c.RendezvousBoot),
+			Emulator: c.Emulator,
+		}, c.Backend)
 		c.pending[RendezvousBoot] = append(c.pending[RendezvousBoot], r)
 		rendezvousNodes = append(rendezvousNodes, r.Addr())
 	}
@@ -186,49 +309,63 @@ func (c *Cluster) create(ctx context.Context, opts ScaleOpts) error {
 
 	for i := mails; i < mails+opts.Mails; i++ {
 		cfg := DefaultConfig()
+		cfg.Discovery = !opts.NoDiscovery
+		cfg.Type = Mail
 		cfg.Name = c.getName(string(Mail), strconv.Itoa(i))
 		cfg.NetID = netID
-		cfg.IP = c.IPAM.Take().String()
+		cfg.Emulator = c.Emulator
+		ip, err := c.IPAM.Take()
+		if err != nil {
+			return err
+		}
+		cfg.IP = ip.String()
 		cfg.BootNodes = enodes
 		cfg.RendezvousNodes = rendezvousNodes
-		cfg.Image = c.Statusd
-		cfg.Mailserver = true
-		cfg.TopicSearch = map[string]string{
-			"whisper": "3,5",
-		}
-		cfg.TopicRegister = []string{"mail"}
+		cfg.Image = opts.image(Mail, i-mails, c.Statusd)
+		opts.apply(Mail, &cfg)
 		p := NewStatusd(cfg, c.Backend)
 		c.pending[Mail] = append(c.pending[Mail], p)
 	}
 
 	for i := relay; i < relay+opts.Relay; i++ {
 		cfg := DefaultConfig()
+		cfg.Discovery = !opts.NoDiscovery
+		cfg.Type = Relay
 		cfg.Name = c.getName(string(Relay), strconv.Itoa(i))
 		cfg.NetID = netID
-		cfg.IP = c.IPAM.Take().String()
+		cfg.Emulator = c.Emulator
+		ip, err := c.IPAM.Take()
+		if err != nil {
+			return err
+		}
+		cfg.IP = ip.String()
 		cfg.BootNodes = enodes
 		cfg.RendezvousNodes = rendezvousNodes
-		cfg.Image = c.Statusd
-		cfg.TopicSearch = map[string]string{
-			"whisper": "5,7",
-		}
-		cfg.TopicRegister = []string{"whisper"}
+		cfg.Image = opts.image(Relay, i-relay, c.Statusd)
+		opts.apply(Relay, &cfg)
 		p := NewStatusd(cfg, c.Backend)
 		log.Trace("adding relay peer to pending", "name", cfg.Name, "ip", cfg.IP)
 		c.pending[Relay] = append(c.pending[Relay], p)
 	}
+	var nat *natGroup
+	if opts.NAT != nil {
+		nat = &natGroup{cluster: c, opts: *opts.NAT, netID: netID, image: c.Statusd}
+	}
 	for i := users; i < users+opts.Users; i++ {
 		cfg := DefaultConfig()
+		cfg.Discovery = !opts.NoDiscovery
+		cfg.Type = User
 		cfg.Name = c.getName(string(User), strconv.Itoa(i))
 		cfg.NetID = netID
-		cfg.Image = c.Client
-		cfg.IP = c.IPAM.Take().String()
+		cfg.Emulator = c.Emulator
+		cfg.Image = opts.image(User, i-users, c.Client)
+		if err := c.assign(ctx, nat, &cfg); err != nil {
+			return err
+		}
 		cfg.BootNodes = enodes
 		cfg.RendezvousNodes = rendezvousNodes
 		cfg.Mailservers = mailservers
-		cfg.TopicSearch = map[string]string{
-			"whisper": "2,2",
-		}
+		opts.apply(User, &cfg)
 		identity, err := crypto.GenerateKey()
 		if err != nil {
 			return err
@@ -239,16 +376,19 @@ func (c *Cluster) create(ctx context.Context, opts ScaleOpts) error {
 	}
 	for i := mvds; i < mvds+opts.MVDS; i++ {
 		cfg := DefaultConfig()
+		cfg.Discovery = !opts.NoDiscovery
+		cfg.Type = MVDS
 		cfg.Name = c.getName(string(MVDS), strconv.Itoa(i))
 		cfg.NetID = netID
-		cfg.Image = c.Client
-		cfg.IP = c.IPAM.Take().String()
+		cfg.Emulator = c.Emulator
+		cfg.Image = opts.image(MVDS, i-mvds, c.Client)
+		if err := c.assign(ctx, nat, &cfg); err != nil {
+			return err
+		}
 		cfg.BootNodes = enodes
 		cfg.RendezvousNodes = rendezvousNodes
 		cfg.Mailservers = mailservers
-		cfg.TopicSearch = map[string]string{
-			"whisper": "2,2",
-		}
+		opts.apply(MVDS, &cfg)
 		identity, err := crypto.GenerateKey()
 		if err != nil {
 			return err
@@ -257,12 +397,45 @@ func (c *Cluster) create(ctx context.Context, opts ScaleOpts) error {
 		log.Trace("adding mvds peer to pending", "name", cfg.Name, "ip", cfg.IP)
 		c.pending[MVDS] = append(c.pending[MVDS], p)
 	}
-	if opts.Deploy {
-		return c.DeployPending(ctx)
+	for typ, count := range opts.counts() {
+		c.created[typ] += count
 	}
 	return nil
 }
 
+// dropPending removes pending peers that were added after pending had given lengths and releases their addresses.
+func (c *Cluster) dropPending(ctx context.Context, pending map[PeerType]int) {
+	for typ, nodes := range c.pending {
+		for _, n := range nodes[pending[typ]:] {
+			if g, ok := n.(*Gateway); ok {
+				if err := g.removeNetwork(ctx); err != nil {
+					log.Error("failed to remove private network", "gateway", n, "error", err)
+				}
+			}
+			if behindNAT(n) {
+				continue
+			}
+			if err := c.IPAM.Release(net.ParseIP(n.IP())); err != nil {
+				log.Error("failed to release ip", "peer", n, "error", err)
+			}
+		}
+		c.pending[typ] = nodes[:pending[typ]]
+	}
+}
+
+// assign allocates address for the peer from the cluster network, or puts it behind the gateway if nat is not nil.
+func (c *Cluster) assign(ctx context.Context, nat *natGroup, cfg *PeerConfig) error {
+	if nat != nil {
+		return nat.assign(ctx, cfg)
+	}
+	ip, err := c.IPAM.Take()
+	if err != nil {
+		return err
+	}
+	cfg.IP = ip.String()
+	return nil
+}
+
 func (c *Cluster) DeployPending(ctx context.Context) error {
 	c.mu.Lock()
 	defer c.mu.Unlock()
@@ -276,7 +449,7 @@ func (c *Cluster) DeployPending(ctx context.Context) error {
 		for i := range peers {
 			p := peers[i]
 			run.Run(func(ctx context.Context) error {
-				err := p.(Creatable).Create(ctx)
+				err := p.Create(ctx)
 				if err != nil {
 					return fmt.Errorf("error creating %v: %v", p, err)
 				}
@@ -292,8 +465,102 @@ func (c *Cluster) DeployPending(ctx context.Context) error {
 	for typ := range c.pending {
 		c.running[typ] = append(c.running[typ], c.pending[typ]...)
 	}
-	c.pending = map[PeerType][]interface{}{}
-	return nil
+	c.pending = map[PeerType][]Node{}
+	return c.save()
+}
+
+// Remove stops and removes most recently deployed peers of every type in opts.
+func (c *Cluster) Remove(ctx context.Context, opts ScaleOpts) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var nodes []Node
+	for typ, count := range opts.counts() {
+		running := c.running[typ]
+		if count > len(running) {
+			return fmt.Errorf("can't remove %d peers of type %s, only %d are running", count, typ, len(running))
+		}
+		nodes = append(nodes, running[len(running)-count:]...)
+	}
+	return c.remove(ctx, nodes)
+}
+
+// RemovePeer stops and removes a single running peer.
+func (c *Cluster) RemovePeer(ctx context.Context, uid string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, n := range c.nodes() {
+		if n.UID() == uid {
+			return c.remove(ctx, []Node{n})
+		}
+	}
+	return fmt.Errorf("peer %s is not running", uid)
+}
+
+// remove removes containers concurrently. Every removed peer is dropped from running peers
+// and its ip is released, even if removal of other peers failed.
+func (c *Cluster) remove(ctx context.Context, nodes []Node) error {
+	errors := make([]error, len(nodes))
+	group := utils.NewGroup(ctx, len(nodes))
+	for i := range nodes {
+		i := i
+		group.Run(func(ctx context.Context) error {
+			errors[i] = nodes[i].Remove(ctx)
+			if errors[i] != nil {
+				return fmt.Errorf("error removing %v: %v", nodes[i], errors[i])
+			}
+			return nil
+		})
+	}
+	err := group.Error()
+	for i, n := range nodes {
+		if errors[i] != nil {
+			continue
+		}
+		c.drop(n)
+		if g, ok := n.(*Gateway); ok {
+			if err := g.removeNetwork(ctx); err != nil {
+				log.Error("failed to remove private network", "gateway", n, "error", err)
+			}
+		}
+		if behindNAT(n) {
+			continue
+		}
+		if err := c.IPAM.Release(net.ParseIP(n.IP())); err != nil {
+			log.Error("failed to release ip", "peer", n, "error", err)
+		}
+	}
+	if serr := c.save(); serr != nil {
+		log.Error("failed to save cluster state", "error", serr)
+	}
+	return err
+}
+
+func (c *Cluster) drop(n Node) {
+	for typ, nodes := range c.running {
+		for i := range nodes {
+			if nodes[i].UID() == n.UID() {
+				c.running[typ] = append(nodes[:i:i], nodes[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// GetNodes returns running nodes of the given types. If types are empty all running nodes are returned.
+func (c *Cluster) GetNodes(types ...PeerType) []Node {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.nodes(types...)
+}
+
+func (c *Cluster) nodes(types ...PeerType) (rst []Node) {
+	if len(types) == 0 {
+		types = AllTypes
+	}
+	for _, typ := range types {
+		rst = append(rst, c.running[typ]...)
+	}
+	return rst
 }
 
 func (c *Cluster) GetRelays() []*Peer {
@@ -304,6 +571,27 @@ func (c *Cluster) GetRelays() []*Peer {
 	return rst
 }
 
+// GetPeers returns running status-go peers of the given types. If types are empty all peers are returned.
+func (c *Cluster) GetPeers(types ...PeerType) (rst []*Peer) {
+	for _, n := range c.GetNodes(types...) {
+		switch v := n.(type) {
+		case *Peer:
+			rst = append(rst, v)
+		case *Client:
+			rst = append(rst, v.Peer)
+		}
+	}
+	return rst
+}
+
+func (c *Cluster) GetMails() []*Peer {
+	rst := make([]*Peer, len(c.running[Mail]))
+	for i := range c.running[Mail] {
+		rst[i] = c.running[Mail][i].(*Peer)
+	}
+	return rst
+}
+
 func (c *Cluster) GetUsers() []*Client {
 	rst := make([]*Client, len(c.running[User]))
 	for i := range c.running[User] {
@@ -401,44 +689,92 @@ func (c *Cluster) Clean(ctx context.Context) {
 	defer c.mu.Unlock()
 	for _, peers := range c.running {
 		for _, p := range peers {
-			if err := p.(Removable).Remove(ctx); err != nil {
+			if err := p.Remove(ctx); err != nil {
 				log.Error("error removing", "peer", p, "error", err)
 			}
 		}
 	}
+	// private networks can be removed only after all peers behind gateways are removed
+	for _, n := range c.running[NATGateway] {
+		if err := n.(*Gateway).removeNetwork(ctx); err != nil {
+			log.Error("error removing private network", "gateway", n, "error", err)
+		}
+	}
 	log.Debug("removing network", "id", c.netID, "name", c.getName("net"))
 	if err := c.Backend.RemoveNetwork(ctx, c.netID); err != nil {
 		log.Error("error removing", "network", c.getName("net"), "error", err)
 	}
+	if len(c.RunDir) != 0 {
+		if err := os.Remove(c.statePath()); err != nil && !os.IsNotExist(err) {
+			log.Error("error removing cluster state", "path", c.statePath(), "error", err)
+		}
+	}
 }
 
-func (c *Cluster) EnableConditionsGloobally(ctx context.Context, opt network.Options) error {
-	total := 0
-	// FIXME(dshulyak) set interface for a peer. i am using set of methods in the cluster
-	// that every peer should provide for correctness.
-	for _, peers := range c.running {
-		total += len(peers)
+func (c *Cluster) EnableConditionsGloobally(ctx context.Context, opts ...network.Options) error {
+	nodes := c.GetNodes()
+	group := utils.NewGroup(ctx, len(nodes))
+	for i := range nodes {
+		n := nodes[i]
+		group.Run(func(ctx context.Context) error {
+			return n.EnableConditions(ctx, opts...)
+		})
 	}
-	group := utils.NewGroup(ctx, total)
-	for _, peers := range c.running {
-		for _, p := range peers {
-			typed := p.(Enforsable)
-			group.Run(func(ctx context.Context) error {
-				return typed.EnableConditions(ctx, opt)
-			})
-		}
+	return group.Error()
+}
+
+func (c *Cluster) DisableConditionsGloobally(ctx context.Context, opts ...network.Options) error {
+	nodes := c.GetNodes()
+	group := utils.NewGroup(ctx, len(nodes))
+	for i := range nodes {
+		n := nodes[i]
+		group.Run(func(ctx context.Context) error {
+			return n.DisableConditions(ctx, opts...)
+		})
 	}
 	return group.Error()
 }
 
-func (c *Cluster) AllIPs() (rst []string) {
-	for _, group := range c.running {
-		for _, p := range group {
-			a, ok := p.(AssignedIP)
-			if ok {
-				rst = append(rst, a.IP())
-			}
+// ActiveConditions returns network conditions that are enabled on running nodes, by node uid.
+// Nodes without conditions are omitted.
+func (c *Cluster) ActiveConditions() map[string][]network.Options {
+	rst := map[string][]network.Options{}
+	for _, n := range c.GetNodes() {
+		if active := n.ActiveConditions(); len(active) != 0 {
+			rst[n.UID()] = active
 		}
 	}
 	return rst
 }
+
+// EnablePreset enables network preset on every node of the given types for traffic inside of the cluster.
+func (c *Cluster) EnablePreset(ctx context.Context, name string, types ...PeerType) error {
+	return c.preset(ctx, name, true, types)
+}
+
+// DisablePreset disables network preset that was enabled with EnablePreset.
+func (c *Cluster) DisablePreset(ctx context.Context, name string, types ...PeerType) error {
+	return c.preset(ctx, name, false, types)
+}
+
+func (c *Cluster) preset(ctx context.Context, name string, enable bool, types []PeerType) error {
+	opts, err := network.PresetOptions(name, c.IPAM.String())
+	if err != nil {
+		return err
+	}
+	var rules []rule
+	for _, n := range c.GetNodes(types...) {
+		rules = append(rules, rule{node: n, opts: opts})
+	}
+	if enable {
+		return enableRules(ctx, rules)
+	}
+	return disableRules(ctx, rules)
+}
+
+func (c *Cluster) AllIPs() (rst []string) {
+	for _, n := range c.GetNodes() {
+		rst = append(rst, n.IP())
+	}
+	return rst
+}
@@ -0,0 +1,228 @@
+package cluster
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+	"net"
+	"os"
+	"testing"
+
+	"github.com/status-im/status-console-client/protocol/gethservice"
+	"github.com/stretchr/testify/require"
+
+	"github.com/status-im/status-scale/cluster/fakebackend"
+	"github.com/status-im/status-scale/network"
+)
+
+func TestDeployWithFakeBackend(t *testing.T) {
+	backend := fakebackend.New()
+	defer backend.Close()
+	backend.Metrics = func(id string) (json.RawMessage, error) {
+		return json.RawMessage(`{"p2p": {"InboundTraffic": {"Overall": 10}}}`), nil
+	}
+	ipam, err := NewIPAM("10.0.0.0/24")
+	require.NoError(t, err)
+	c := NewCluster("test", ipam, backend, "statusd", "client", "bootnode", "rendezvous", false)
+	require.NoError(t, c.Create(context.TODO(), ScaleOpts{Boot: 1, Mails: 1, Relay: 2, Users: 1, Deploy: true}))
+	require.Equal(t, []string{"test_boot_0", "test_mail_0", "test_relay_0", "test_relay_1", "test_user_0"}, backend.Containers())
+
+	// ips are assigned in the order of deployment
+	require.Equal(t, "10.0.0.2", c.GetBootnode(0).IP())
+	require.Equal(t, "10.0.0.6", c.GetUser(0).IP())
+	opts, exist := backend.Opts("test_user_0")
+	require.True(t, exist)
+	require.Equal(t, "10.0.0.6", opts.IPs[c.netID].IP)
+	require.Contains(t, c.GetMail(0).Enode(), "@10.0.0.3:30303")
+
+	payload, err := c.GetRelay(0).RawMetrics(context.TODO())
+	require.NoError(t, err)
+	require.JSONEq(t, `{"p2p": {"InboundTraffic": {"Overall": 10}}}`, string(payload))
+
+	enode := c.GetRelay(1).Enode()
+	require.NoError(t, c.GetRelay(1).Reboot(context.TODO()))
+	require.Equal(t, enode, c.GetRelay(1).Enode())
+
+	require.NoError(t, c.RemovePeer(context.TODO(), "test_relay_0"))
+	require.Len(t, c.GetRelays(), 1)
+	// released ip is reused by a new relay
+	require.NoError(t, c.Create(context.TODO(), ScaleOpts{Relay: 1, Deploy: true}))
+	require.Equal(t, "test_relay_2", c.GetRelay(1).UID())
+	require.Equal(t, "10.0.0.4", c.GetRelay(1).IP())
+
+	c.Clean(context.TODO())
+	require.Empty(t, backend.Containers())
+	require.Len(t, backend.Calls("Create"), 6)
+	require.Len(t, backend.Calls("Remove"), 6)
+	require.Empty(t, backend.Volumes())
+	require.Len(t, backend.Calls("RemoveNetwork"), 1)
+}
+
+func TestVerifyTargetWithRegions(t *testing.T) {
+	backend := fakebackend.New()
+	defer backend.Close()
+	ipam, err := NewIPAM("10.0.0.0/24")
+	require.NoError(t, err)
+	c := NewCluster("test", ipam, backend, "statusd", "client", "bootnode", "rendezvous", false)
+	require.NoError(t, c.Create(context.TODO(), ScaleOpts{Relay: 3, Deploy: true}))
+	defer c.Clean(context.TODO())
+
+	relays := c.GetRelays()
+	matrix := network.LatencyMatrix{}
+	matrix.Set("a", "a", 40)
+	require.NoError(t, c.ApplyRegions(context.TODO(), matrix, map[string][]Node{"a": {relays[0], relays[1]}}))
+	opts := network.Options{PacketLoss: 5, TargetAddrs: []string{"10.0.0.0/24"}}
+	require.NoError(t, relays[0].EnableConditions(context.TODO(), opts))
+
+	// second relay delays traffic to the first one, so it can't be a target
+	target, err := c.verifyTarget(relays[0], opts)
+	require.NoError(t, err)
+	require.Equal(t, relays[2].UID(), target.UID())
+
+	// region delay is merged with latency of the conditions
+	effective, err := network.Effective(relays[0].ActiveConditions(), relays[1].IP())
+	require.NoError(t, err)
+	require.Equal(t, network.Options{Latency: 20, PacketLoss: 5}, effective)
+}
+
+func TestUpgradeKeepsDataAndConditions(t *testing.T) {
+	backend := fakebackend.New()
+	defer backend.Close()
+	ipam, err := NewIPAM("10.0.0.0/24")
+	require.NoError(t, err)
+	c := NewCluster("test", ipam, backend, "statusd", "client", "bootnode", "rendezvous", false)
+	require.NoError(t, c.Create(context.TODO(), ScaleOpts{Mails: 1, Deploy: true}))
+	defer c.Clean(context.TODO())
+
+	mail := c.GetMail(0)
+	chat := gethservice.Contact{Name: "chat"}
+	require.NoError(t, mail.Rpc().CallContext(context.TODO(), nil, "ssm_addContact", chat))
+	require.NoError(t, mail.Rpc().CallContext(context.TODO(), nil, "ssm_sendToContact", chat, "kept"))
+	opts := network.Options{Latency: 100}
+	require.NoError(t, mail.EnableConditions(context.TODO(), opts))
+	executed := len(backend.Calls("Execute"))
+
+	require.NoError(t, c.Upgrade(context.TODO(), ByType(Mail), "statusd:next", 1))
+	created, exist := backend.Opts(mail.UID())
+	require.True(t, exist)
+	require.Equal(t, "statusd:next", created.Image)
+	require.Empty(t, backend.Calls("RemoveVolume"))
+	require.Equal(t, []network.Options{opts}, c.GetMail(0).ActiveConditions())
+	require.True(t, len(backend.Calls("Execute")) > executed, "conditions weren't applied to the new container")
+
+	var messages []json.RawMessage
+	require.NoError(t, c.GetMail(0).Rpc().CallContext(context.TODO(), &messages, "ssm_readContactMessages", chat, 0))
+	require.Len(t, messages, 1)
+}
+
+func TestAttachTwice(t *testing.T) {
+	backend := fakebackend.New()
+	defer backend.Close()
+	dir, err := ioutil.TempDir("", "scale-attach-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	opts := ScaleOpts{Boot: 1, Relay: 2, Users: 1, Deploy: true}
+	attach := func() *Cluster {
+		ipam, err := NewIPAM("10.0.0.0/24")
+		require.NoError(t, err)
+		c := NewCluster("test", ipam, backend, "statusd", "client", "bootnode", "rendezvous", true)
+		c.RunDir = dir
+		return c
+	}
+	c := attach()
+	require.NoError(t, c.Create(context.TODO(), opts))
+	created := len(backend.Calls("Create"))
+
+	for i := 0; i < 2; i++ {
+		c := attach()
+		require.NoError(t, c.Attach(context.TODO()))
+		require.True(t, c.Attached())
+		require.NoError(t, c.Create(context.TODO(), opts))
+		require.Len(t, backend.Calls("Create"), created)
+		require.Len(t, c.GetRelays(), 2)
+	}
+
+	// only missing peers are created
+	c = attach()
+	require.NoError(t, c.Attach(context.TODO()))
+	require.NoError(t, c.Create(context.TODO(), ScaleOpts{Relay: 3, Deploy: true}))
+	require.Len(t, backend.Calls("Create"), created+1)
+	require.Equal(t, "test_relay_2", c.GetRelay(2).UID())
+}
+
+func TestRemove(t *testing.T) {
+	backend := fakebackend.New()
+	defer backend.Close()
+	ipam, err := NewIPAM("10.0.0.0/24")
+	require.NoError(t, err)
+	c := NewCluster("test", ipam, backend, "statusd", "client", "bootnode", "rendezvous", false)
+	require.NoError(t, c.Create(context.TODO(), ScaleOpts{Relay: 3, Users: 1, Deploy: true}))
+	defer c.Clean(context.TODO())
+
+	require.Error(t, c.Remove(context.TODO(), ScaleOpts{Users: 2}))
+	require.Len(t, c.GetUsers(), 1)
+
+	// most recent relays are removed
+	require.NoError(t, c.Remove(context.TODO(), ScaleOpts{Relay: 2}))
+	relays := c.GetRelays()
+	require.Len(t, relays, 1)
+	require.Equal(t, "test_relay_0", relays[0].UID())
+	require.Equal(t, []string{"test_relay_0", "test_user_0"}, backend.Containers())
+
+	// names are not reused, ips are
+	require.NoError(t, c.Create(context.TODO(), ScaleOpts{Relay: 1, Deploy: true}))
+	require.Equal(t, "test_relay_3", c.GetRelay(1).UID())
+	require.Equal(t, "10.0.0.3", c.GetRelay(1).IP())
+
+	require.Error(t, c.RemovePeer(context.TODO(), "test_relay_1"))
+	// peer that failed to be removed is kept running
+	require.NoError(t, backend.Remove(context.TODO(), "test_user_0"))
+	require.Error(t, c.RemovePeer(context.TODO(), "test_user_0"))
+	require.Len(t, c.GetUsers(), 1)
+}
+
+func TestCreateExhaustedBatch(t *testing.T) {
+	backend := fakebackend.New()
+	defer backend.Close()
+	ipam, err := NewIPAM("10.0.0.0/29")
+	require.NoError(t, err)
+	c := NewCluster("test", ipam, backend, "statusd", "client", "bootnode", "rendezvous", false)
+	require.NoError(t, c.Create(context.TODO(), ScaleOpts{Relay: 3, Deploy: true}))
+	defer c.Clean(context.TODO())
+
+	// batch is dropped when network runs out of addresses in the middle of it
+	require.Equal(t, ErrIPAMExhausted, c.Create(context.TODO(), ScaleOpts{Relay: 3, Deploy: true}))
+	require.Empty(t, c.pending[Relay])
+	// second gateway can't get an address, private network of the first one is removed
+	nat := &NATOpts{PeersPerGateway: 1}
+	require.Equal(t, ErrIPAMExhausted, c.Create(context.TODO(), ScaleOpts{Relay: 1, Users: 2, NAT: nat, Deploy: true}))
+	require.Empty(t, c.pending[NATGateway])
+	require.Len(t, backend.Calls("RemoveNetwork"), 1)
+
+	require.NoError(t, c.Create(context.TODO(), ScaleOpts{Relay: 2, Deploy: true}))
+	require.Equal(t, []string{"test_relay_0", "test_relay_1", "test_relay_2", "test_relay_3", "test_relay_4"}, backend.Containers())
+}
+
+func TestAttachConflict(t *testing.T) {
+	backend := fakebackend.New()
+	defer backend.Close()
+	dir, err := ioutil.TempDir("", "scale-attach-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	ipam, err := NewIPAM("10.0.0.0/24")
+	require.NoError(t, err)
+	c := NewCluster("test", ipam, backend, "statusd", "client", "bootnode", "rendezvous", true)
+	c.RunDir = dir
+	require.NoError(t, c.Create(context.TODO(), ScaleOpts{Relay: 2, Deploy: true}))
+
+	ipam, err = NewIPAM("10.0.0.0/24")
+	require.NoError(t, err)
+	require.NoError(t, ipam.Reserve(net.ParseIP("10.0.0.3")))
+	c = NewCluster("test", ipam, backend, "statusd", "client", "bootnode", "rendezvous", true)
+	c.RunDir = dir
+	require.Error(t, c.Attach(context.TODO()))
+	require.False(t, c.Attached())
+	require.Empty(t, c.GetRelays())
+	// address of the first relay was released
+	require.NoError(t, ipam.Reserve(net.ParseIP("10.0.0.2")))
+}
@@ -0,0 +1,107 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/status-im/status-scale/network"
+)
+
+var (
+	ErrConditionsNotActive = errors.New("conditions are not active")
+)
+
+// conditions tracks network conditions that are enabled on a node. Emulator replaces all conditions
+// on every change, so that several conditions can be active and removed one by one. Conditions that target
+// the same destination are merged by the emulator, see network.Merge.
+type conditions struct {
+	mu       sync.Mutex
+	emulator network.Emulator
+	active   []network.Options
+}
+
+func newConditions(emulator network.Emulator) *conditions {
+	if emulator == nil {
+		emulator = network.Netem{}
+	}
+	return &conditions{emulator: emulator}
+}
+
+// list returns a copy of active conditions in the order in which they were enabled.
+func (c *conditions) list() []network.Options {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]network.Options{}, c.active...)
+}
+
+func (c *conditions) enable(ctx context.Context, shell network.Executor, opts ...network.Options) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(opts) == 0 {
+		return nil
+	}
+	next := append(c.active[:len(c.active):len(c.active)], opts...)
+	if err := c.emulator.Apply(ctx, shell, next...); err != nil {
+		return err
+	}
+	c.active = next
+	return nil
+}
+
+// disable removes given conditions. If opts are empty all conditions are removed.
+// Conditions that are active are removed even if some of opts are not active.
+func (c *conditions) disable(ctx context.Context, shell network.Executor, opts ...network.Options) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var (
+		next    []network.Options
+		missing []network.Options
+	)
+	if len(opts) != 0 {
+		next, missing = without(c.active, opts)
+	}
+	// without opts rules are removed even if nothing is tracked, e.g. after attaching to a kept cluster
+	if len(opts) == 0 || len(next) != len(c.active) {
+		if err := c.emulator.Apply(ctx, shell, next...); err != nil {
+			return err
+		}
+		c.active = next
+	}
+	if len(missing) != 0 {
+		return fmt.Errorf("%v: %+v", ErrConditionsNotActive, missing)
+	}
+	return nil
+}
+
+// reapply applies active conditions again, rules are lost when container is restarted or recreated.
+func (c *conditions) reapply(ctx context.Context, shell network.Executor) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.active) == 0 {
+		return nil
+	}
+	return c.emulator.Apply(ctx, shell, c.active...)
+}
+
+// without removes first equal options from active for each of opts. Options that were not found are returned
+// as missing.
+func without(active, opts []network.Options) (rst, missing []network.Options) {
+	rst = append([]network.Options{}, active...)
+	for _, opt := range opts {
+		found := false
+		for i := range rst {
+			if reflect.DeepEqual(rst[i], opt) {
+				rst = append(rst[:i], rst[i+1:]...)
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, opt)
+		}
+	}
+	return rst, missing
+}
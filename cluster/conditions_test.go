@@ -0,0 +1,44 @@
+package cluster
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/status-im/status-scale/network"
+)
+
+type recorder struct {
+	applied [][]network.Options
+}
+
+func (r *recorder) Apply(ctx context.Context, shell network.Executor, options ...network.Options) error {
+	r.applied = append(r.applied, options)
+	return nil
+}
+
+func TestConditionsStacking(t *testing.T) {
+	var (
+		emulator  = &recorder{}
+		c         = newConditions(emulator)
+		latency   = network.Options{Latency: 100, TargetAddrs: []string{"10.0.0.0/24"}}
+		blackhole = network.Options{PacketLoss: 100, TargetAddrs: []string{"10.0.0.0/24"}}
+	)
+	require.NoError(t, c.enable(context.TODO(), nil, latency))
+	require.NoError(t, c.enable(context.TODO(), nil, blackhole))
+	require.Equal(t, []network.Options{latency, blackhole}, c.list())
+
+	require.NoError(t, c.disable(context.TODO(), nil, blackhole))
+	require.Equal(t, []network.Options{latency}, c.list())
+	require.Equal(t, []network.Options{latency}, emulator.applied[len(emulator.applied)-1])
+
+	err := c.disable(context.TODO(), nil, blackhole)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), ErrConditionsNotActive.Error())
+	require.Equal(t, []network.Options{latency}, c.list())
+
+	require.NoError(t, c.disable(context.TODO(), nil))
+	require.Empty(t, c.list())
+	require.Empty(t, emulator.applied[len(emulator.applied)-1])
+}
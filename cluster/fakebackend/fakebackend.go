@@ -0,0 +1,452 @@
+// Package fakebackend implements cluster backend in memory, so that cluster logic can be tested without docker.
+package fakebackend
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"sync"
+
+	"github.com/docker/go-connections/nat"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/status-im/status-console-client/protocol/gethservice"
+
+	"github.com/status-im/status-scale/dockershim"
+)
+
+// State of a container.
+type State string
+
+const (
+	Running State = "running"
+	Paused  State = "paused"
+	Stopped State = "stopped"
+)
+
+// Call is a recorded call to the backend. Cmd is a command of Create, Execute and Output.
+// Reads of chat messages over rpc are recorded as ReadContactMessages.
+type Call struct {
+	Method string
+	ID     string
+	Cmd    []string
+}
+
+// New returns backend without containers. Close must be called to shutdown rpc servers.
+func New() *Backend {
+	return &Backend{
+		containers: map[string]*container{},
+		networks:   map[string]dockershim.NetOpts{},
+		volumes:    map[string]*data{},
+	}
+}
+
+// Backend keeps containers in memory. Every container serves admin, debug and ssm rpc modules
+// on a local http server while it is running. Network conditions are not emulated: messages are delivered
+// to every running container with a contact of the same name, and containers that weren't running
+// receive messages only after they request them. Chat data of containers with a volume is kept in the volume
+// until it is removed.
+type Backend struct {
+	// Exec handles commands that are executed in containers. Commands succeed with empty output if nil.
+	Exec func(id string, cmd []string) (string, error)
+	// Metrics returns payload of debug_metrics. Empty object is returned if nil.
+	Metrics func(id string) (json.RawMessage, error)
+
+	mu         sync.Mutex
+	calls      []Call
+	containers map[string]*container
+	networks   map[string]dockershim.NetOpts
+	volumes    map[string]*data
+	// archive stores every sent message, as if mail servers were always available
+	archive []envelope
+	clock   int64
+}
+
+type container struct {
+	opts   dockershim.CreateOpts
+	state  State
+	info   p2p.NodeInfo
+	rpc    *rpc.Server
+	server *httptest.Server
+	*data
+}
+
+// data of a container is kept in a volume if container was created with one.
+type data struct {
+	contacts map[string]gethservice.Contact
+	// messages are read by rowid, which is an index in messages plus one
+	messages  []envelope
+	delivered map[int]bool
+	requests  []Request
+}
+
+func (b *Backend) record(method, id string, cmd []string) {
+	b.calls = append(b.calls, Call{Method: method, ID: id, Cmd: cmd})
+}
+
+// Calls returns recorded calls with one of the methods in the order in which they were made.
+// All calls are returned if methods are empty.
+func (b *Backend) Calls(methods ...string) (rst []Call) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for _, call := range b.calls {
+		if len(methods) == 0 {
+			rst = append(rst, call)
+			continue
+		}
+		for _, method := range methods {
+			if call.Method == method {
+				rst = append(rst, call)
+				break
+			}
+		}
+	}
+	return rst
+}
+
+// Containers returns sorted names of existing containers.
+func (b *Backend) Containers() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	rst := make([]string, 0, len(b.containers))
+	for id := range b.containers {
+		rst = append(rst, id)
+	}
+	sort.Strings(rst)
+	return rst
+}
+
+// Volumes returns sorted names of existing volumes.
+func (b *Backend) Volumes() []string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	rst := make([]string, 0, len(b.volumes))
+	for name := range b.volumes {
+		rst = append(rst, name)
+	}
+	sort.Strings(rst)
+	return rst
+}
+
+// State returns state of the container, false if container doesn't exist.
+func (b *Backend) State(id string) (State, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c, exist := b.containers[id]
+	if !exist {
+		return "", false
+	}
+	return c.state, true
+}
+
+// Opts returns options that were used to create the container.
+func (b *Backend) Opts(id string) (dockershim.CreateOpts, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c, exist := b.containers[id]
+	if !exist {
+		return dockershim.CreateOpts{}, false
+	}
+	return c.opts, true
+}
+
+// Requests returns requests for messages that were made by the container.
+func (b *Backend) Requests(id string) []Request {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c, exist := b.containers[id]
+	if !exist {
+		return nil
+	}
+	return append([]Request{}, c.requests...)
+}
+
+// Close shuts down rpc servers of all containers.
+func (b *Backend) Close() {
+	b.mu.Lock()
+	containers := b.containers
+	b.containers = map[string]*container{}
+	b.mu.Unlock()
+	for _, c := range containers {
+		c.close()
+	}
+}
+
+func (c *container) close() {
+	c.server.Close()
+	c.rpc.Stop()
+}
+
+// get must be called with lock held.
+func (b *Backend) get(id string) (*container, error) {
+	c, exist := b.containers[id]
+	if !exist {
+		return nil, fmt.Errorf("no such container: %s", id)
+	}
+	return c, nil
+}
+
+func (b *Backend) Execute(ctx context.Context, id string, cmd []string) error {
+	_, err := b.exec("Execute", id, cmd)
+	return err
+}
+
+func (b *Backend) Output(ctx context.Context, id string, cmd []string) (string, error) {
+	return b.exec("Output", id, cmd)
+}
+
+func (b *Backend) exec(method, id string, cmd []string) (string, error) {
+	b.mu.Lock()
+	b.record(method, id, cmd)
+	c, err := b.get(id)
+	if err != nil {
+		b.mu.Unlock()
+		return "", err
+	}
+	state := c.state
+	b.mu.Unlock()
+	if state != Running {
+		return "", fmt.Errorf("container %s is %s", id, state)
+	}
+	if b.Exec == nil {
+		return "", nil
+	}
+	return b.Exec(id, cmd)
+}
+
+func (b *Backend) Create(ctx context.Context, id string, opts dockershim.CreateOpts) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.record("Create", id, opts.Cmd)
+	if _, exist := b.containers[id]; exist {
+		return fmt.Errorf("container %s already exists", id)
+	}
+	info, err := nodeInfo(opts)
+	if err != nil {
+		return fmt.Errorf("failed to generate node info for %s: %v", id, err)
+	}
+	c := &container{
+		opts:  opts,
+		state: Running,
+		info:  info,
+		rpc:   rpc.NewServer(),
+		data:  &data{contacts: map[string]gethservice.Contact{}, delivered: map[int]bool{}},
+	}
+	for name := range opts.Volumes {
+		if v, exist := b.volumes[name]; exist {
+			c.data = v
+		}
+		b.volumes[name] = c.data
+	}
+	apis := map[string]interface{}{
+		"admin": &AdminAPI{b: b, id: id},
+		"debug": &DebugAPI{b: b, id: id},
+		"ssm":   &SSMAPI{b: b, id: id},
+	}
+	for name, api := range apis {
+		if err := c.rpc.RegisterName(name, api); err != nil {
+			return err
+		}
+	}
+	c.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if state, _ := b.State(id); state != Running {
+			http.Error(w, fmt.Sprintf("container %s is not running", id), http.StatusServiceUnavailable)
+			return
+		}
+		c.rpc.ServeHTTP(w, r)
+	}))
+	b.containers[id] = c
+	return nil
+}
+
+// nodeInfo uses node key and listen address from status-go config if it was mounted into container.
+func nodeInfo(opts dockershim.CreateOpts) (info p2p.NodeInfo, err error) {
+	cfg := struct {
+		NodeKey    string
+		ListenAddr string
+	}{}
+	if len(opts.HostConfigPath) != 0 {
+		data, err := ioutil.ReadFile(opts.HostConfigPath)
+		if err != nil {
+			return info, err
+		}
+		if err := json.Unmarshal(data, &cfg); err != nil {
+			return info, err
+		}
+	}
+	var key *ecdsa.PrivateKey
+	if len(cfg.NodeKey) != 0 {
+		key, err = crypto.HexToECDSA(cfg.NodeKey)
+	} else {
+		key, err = crypto.GenerateKey()
+	}
+	if err != nil {
+		return info, err
+	}
+	if len(cfg.ListenAddr) == 0 {
+		ip := "127.0.0.1"
+		for _, opts := range opts.IPs {
+			ip = opts.IP
+		}
+		cfg.ListenAddr = net.JoinHostPort(ip, "30303")
+	}
+	host, portStr, err := net.SplitHostPort(cfg.ListenAddr)
+	if err != nil {
+		return info, err
+	}
+	port, err := strconv.Atoi(portStr)
+	if err != nil {
+		return info, err
+	}
+	node := enode.NewV4(&key.PublicKey, net.ParseIP(host), port, port)
+	info.ID = node.ID().String()
+	info.Name = "fakebackend"
+	info.Enode = node.String()
+	info.IP = host
+	info.ListenAddr = cfg.ListenAddr
+	info.Ports.Discovery = port
+	info.Ports.Listener = port
+	return info, nil
+}
+
+func (b *Backend) Remove(ctx context.Context, id string) error {
+	b.mu.Lock()
+	b.record("Remove", id, nil)
+	c, err := b.get(id)
+	if err != nil {
+		b.mu.Unlock()
+		return err
+	}
+	delete(b.containers, id)
+	b.mu.Unlock()
+	// server waits for active requests, which may need a lock
+	c.close()
+	return nil
+}
+
+// RemoveVolume removes data of containers that were created with the volume. Missing volumes are ignored.
+func (b *Backend) RemoveVolume(ctx context.Context, name string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.record("RemoveVolume", name, nil)
+	delete(b.volumes, name)
+	return nil
+}
+
+func (b *Backend) EnsureNetwork(ctx context.Context, opts dockershim.NetOpts) (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.record("EnsureNetwork", opts.NetName, nil)
+	if _, exist := b.networks[opts.NetID]; exist {
+		return opts.NetID, nil
+	}
+	id := fmt.Sprintf("fakenet%d", len(b.networks))
+	b.networks[id] = opts
+	return id, nil
+}
+
+func (b *Backend) RemoveNetwork(ctx context.Context, id string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.record("RemoveNetwork", id, nil)
+	if _, exist := b.networks[id]; !exist {
+		return fmt.Errorf("no such network: %s", id)
+	}
+	delete(b.networks, id)
+	return nil
+}
+
+func (b *Backend) ConnectNetwork(ctx context.Context, id string, opts dockershim.IpOpts) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.record("ConnectNetwork", id, nil)
+	c, err := b.get(id)
+	if err != nil {
+		return err
+	}
+	if _, exist := b.networks[opts.NetID]; !exist {
+		return fmt.Errorf("no such network: %s", opts.NetID)
+	}
+	ips := map[string]dockershim.IpOpts{opts.NetID: opts}
+	for net, ip := range c.opts.IPs {
+		ips[net] = ip
+	}
+	c.opts.IPs = ips
+	return nil
+}
+
+// ConnectionInfo returns address of the rpc server of the container, regardless of the target port.
+func (b *Backend) ConnectionInfo(ctx context.Context, id string, target int) ([]nat.PortBinding, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	c, err := b.get(id)
+	if err != nil {
+		return nil, err
+	}
+	u, err := url.Parse(c.server.URL)
+	if err != nil {
+		return nil, err
+	}
+	host, port, err := net.SplitHostPort(u.Host)
+	if err != nil {
+		return nil, err
+	}
+	return []nat.PortBinding{{HostIP: host, HostPort: port}}, nil
+}
+
+func (b *Backend) Reboot(ctx context.Context, id string) error {
+	return b.transition("Reboot", id, Running)
+}
+
+func (b *Backend) Pause(ctx context.Context, id string) error {
+	return b.transition("Pause", id, Paused, Running)
+}
+
+func (b *Backend) Unpause(ctx context.Context, id string) error {
+	return b.transition("Unpause", id, Running, Paused)
+}
+
+func (b *Backend) Stop(ctx context.Context, id string) error {
+	return b.transition("Stop", id, Stopped, Running, Paused, Stopped)
+}
+
+func (b *Backend) Kill(ctx context.Context, id string) error {
+	return b.transition("Kill", id, Stopped, Running, Paused)
+}
+
+func (b *Backend) Start(ctx context.Context, id string) error {
+	return b.transition("Start", id, Running, Running, Stopped)
+}
+
+// transition changes state of the container to the given state, if the current state is one of from.
+// Any state is allowed if from is empty.
+func (b *Backend) transition(method, id string, to State, from ...State) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.record(method, id, nil)
+	c, err := b.get(id)
+	if err != nil {
+		return err
+	}
+	if len(from) == 0 {
+		c.state = to
+		return nil
+	}
+	for _, state := range from {
+		if c.state == state {
+			c.state = to
+			return nil
+		}
+	}
+	return fmt.Errorf("%s failed: container %s is %s", method, id, c.state)
+}
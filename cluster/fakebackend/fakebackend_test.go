@@ -0,0 +1,95 @@
+package fakebackend_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/ethereum/go-ethereum/rpc"
+	"github.com/status-im/status-console-client/protocol/gethservice"
+	"github.com/stretchr/testify/require"
+
+	"github.com/status-im/status-scale/client"
+	"github.com/status-im/status-scale/cluster"
+	"github.com/status-im/status-scale/cluster/fakebackend"
+	"github.com/status-im/status-scale/dockershim"
+)
+
+var _ cluster.Backend = (*fakebackend.Backend)(nil)
+
+func dial(t *testing.T, b *fakebackend.Backend, id string) *rpc.Client {
+	ports, err := b.ConnectionInfo(context.TODO(), id, 8545)
+	require.NoError(t, err)
+	c, err := rpc.Dial(fmt.Sprintf("http://%s:%s", ports[0].HostIP, ports[0].HostPort))
+	require.NoError(t, err)
+	return c
+}
+
+func TestNodeInfo(t *testing.T) {
+	b := fakebackend.New()
+	defer b.Close()
+	require.NoError(t, b.Create(context.TODO(), "peer", dockershim.CreateOpts{
+		IPs: map[string]dockershim.IpOpts{"net": {IP: "10.0.0.2", NetID: "net"}},
+	}))
+	info, err := client.AdminClient(dial(t, b, "peer")).Self(context.TODO())
+	require.NoError(t, err)
+	node, err := enode.ParseV4(info.Enode)
+	require.NoError(t, err)
+	require.Equal(t, "10.0.0.2", node.IP().String())
+
+	require.NoError(t, b.Pause(context.TODO(), "peer"))
+	_, err = client.AdminClient(dial(t, b, "peer")).Self(context.TODO())
+	require.Error(t, err)
+	require.Error(t, b.Execute(context.TODO(), "peer", []string{"ip", "a"}))
+	require.Error(t, b.Start(context.TODO(), "peer"))
+	require.NoError(t, b.Unpause(context.TODO(), "peer"))
+
+	require.NoError(t, b.Remove(context.TODO(), "peer"))
+	require.Empty(t, b.Containers())
+	require.Len(t, b.Calls("Create", "Remove"), 2)
+}
+
+func TestMessages(t *testing.T) {
+	b := fakebackend.New()
+	defer b.Close()
+	chat := gethservice.Contact{Name: "chat"}
+	users := make([]client.Chat, 2)
+	for i := range users {
+		id := fmt.Sprintf("user%d", i)
+		require.NoError(t, b.Create(context.TODO(), id, dockershim.CreateOpts{}))
+		users[i] = client.ChatClient(dial(t, b, id))
+		require.NoError(t, users[i].AddContact(context.TODO(), chat))
+	}
+
+	require.NoError(t, users[0].Send(context.TODO(), chat, "live"))
+	msgs, err := users[1].Messages(context.TODO(), chat, 0)
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+	require.Equal(t, "live", msgs[0].Text)
+
+	require.NoError(t, b.Stop(context.TODO(), "user1"))
+	require.NoError(t, users[0].Send(context.TODO(), chat, "missed"))
+	require.NoError(t, b.Start(context.TODO(), "user1"))
+	msgs, err = users[1].Messages(context.TODO(), chat, 2)
+	require.NoError(t, err)
+	require.Empty(t, msgs)
+
+	now := time.Now().Unix()
+	require.NoError(t, users[1].Request(context.TODO(), client.RequestParams{Contact: chat, From: now - 10, To: now + 10}))
+	msgs, err = users[1].Messages(context.TODO(), chat, 2)
+	require.NoError(t, err)
+	require.Len(t, msgs, 1)
+	require.Equal(t, "missed", msgs[0].Text)
+	// delivered messages are not duplicated
+	require.NoError(t, users[1].RequestAll(context.TODO()))
+	msgs, err = users[1].Messages(context.TODO(), chat, 0)
+	require.NoError(t, err)
+	require.Len(t, msgs, 2)
+
+	requests := b.Requests("user1")
+	require.Len(t, requests, 2)
+	require.Equal(t, "chat", requests[0].Name)
+	require.True(t, requests[1].All)
+}
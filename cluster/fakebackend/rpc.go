@@ -0,0 +1,186 @@
+package fakebackend
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/p2p"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+	"github.com/status-im/status-console-client/protocol/gethservice"
+	"github.com/status-im/status-console-client/protocol/v1"
+)
+
+// Request is a recorded request for messages from mail servers. All is true for ssm_requestAll.
+type Request struct {
+	gethservice.Contact
+	Limit int   `json:"limit"`
+	From  int64 `json:"from"`
+	To    int64 `json:"to"`
+	All   bool  `json:"-"`
+}
+
+// envelope is a message in a chat with a contact.
+type envelope struct {
+	chat    string
+	message protocol.Message
+}
+
+// AdminAPI serves admin module of a container. Peers are never connected.
+type AdminAPI struct {
+	b  *Backend
+	id string
+}
+
+func (a *AdminAPI) NodeInfo() (*p2p.NodeInfo, error) {
+	a.b.mu.Lock()
+	defer a.b.mu.Unlock()
+	c, err := a.b.get(a.id)
+	if err != nil {
+		return nil, err
+	}
+	info := c.info
+	return &info, nil
+}
+
+func (a *AdminAPI) Peers() ([]*p2p.PeerInfo, error) {
+	return []*p2p.PeerInfo{}, nil
+}
+
+func (a *AdminAPI) AddPeer(url string) (bool, error) {
+	if _, err := enode.ParseV4(url); err != nil {
+		return false, fmt.Errorf("invalid enode: %v", err)
+	}
+	return true, nil
+}
+
+func (a *AdminAPI) RemovePeer(url string) (bool, error) {
+	return a.AddPeer(url)
+}
+
+// DebugAPI serves metrics of a container.
+type DebugAPI struct {
+	b  *Backend
+	id string
+}
+
+func (d *DebugAPI) Metrics(raw bool) (json.RawMessage, error) {
+	if d.b.Metrics == nil {
+		return json.RawMessage("{}"), nil
+	}
+	return d.b.Metrics(d.id)
+}
+
+// SSMAPI serves chat module of a container.
+type SSMAPI struct {
+	b  *Backend
+	id string
+}
+
+func (s *SSMAPI) AddContact(contact gethservice.Contact) error {
+	s.b.mu.Lock()
+	defer s.b.mu.Unlock()
+	c, err := s.b.get(s.id)
+	if err != nil {
+		return err
+	}
+	c.contacts[contact.Name] = contact
+	return nil
+}
+
+// SendToContact delivers message to the sender and to every running container with a contact of the same name.
+func (s *SSMAPI) SendToContact(contact gethservice.Contact, text string) error {
+	s.b.mu.Lock()
+	defer s.b.mu.Unlock()
+	sender, err := s.b.get(s.id)
+	if err != nil {
+		return err
+	}
+	var msg protocol.Message
+	if len(contact.PublicKey) == 0 {
+		msg = protocol.CreatePublicTextMessage([]byte(text), s.b.clock, contact.Name)
+	} else {
+		msg = protocol.CreatePrivateTextMessage([]byte(text), s.b.clock, contact.Name)
+	}
+	s.b.clock = msg.Clock
+	s.b.archive = append(s.b.archive, envelope{chat: contact.Name, message: msg})
+	index := len(s.b.archive) - 1
+	sender.deliver(index, s.b.archive[index])
+	for _, c := range s.b.containers {
+		if _, exist := c.contacts[contact.Name]; exist && c.state == Running {
+			c.deliver(index, s.b.archive[index])
+		}
+	}
+	return nil
+}
+
+func (c *container) deliver(index int, env envelope) {
+	if c.delivered[index] {
+		return
+	}
+	c.delivered[index] = true
+	c.messages = append(c.messages, env)
+}
+
+// ReadContactMessages returns messages in the chat with the contact with rowid equal or greater than offset.
+func (s *SSMAPI) ReadContactMessages(contact gethservice.Contact, offset int64) ([]*protocol.Message, error) {
+	s.b.mu.Lock()
+	defer s.b.mu.Unlock()
+	s.b.record("ReadContactMessages", s.id, nil)
+	c, err := s.b.get(s.id)
+	if err != nil {
+		return nil, err
+	}
+	if offset < 1 {
+		offset = 1
+	}
+	rst := []*protocol.Message{}
+	for i := int(offset) - 1; i < len(c.messages); i++ {
+		if c.messages[i].chat == contact.Name {
+			msg := c.messages[i].message
+			rst = append(rst, &msg)
+		}
+	}
+	return rst, nil
+}
+
+// RequestAll delivers every archived message in chats with contacts of the container.
+func (s *SSMAPI) RequestAll(force bool) error {
+	s.b.mu.Lock()
+	defer s.b.mu.Unlock()
+	c, err := s.b.get(s.id)
+	if err != nil {
+		return err
+	}
+	c.requests = append(c.requests, Request{All: true})
+	for i, env := range s.b.archive {
+		if _, exist := c.contacts[env.chat]; exist {
+			c.deliver(i, env)
+		}
+	}
+	return nil
+}
+
+// Request delivers archived messages in the chat with the contact that were sent between From and To, up to Limit.
+func (s *SSMAPI) Request(params Request) error {
+	s.b.mu.Lock()
+	defer s.b.mu.Unlock()
+	c, err := s.b.get(s.id)
+	if err != nil {
+		return err
+	}
+	params.All = false
+	c.requests = append(c.requests, params)
+	count := 0
+	for i, env := range s.b.archive {
+		if params.Limit != 0 && count == params.Limit {
+			break
+		}
+		sent := env.message.Timestamp.Time().Unix()
+		if env.chat != params.Name || sent < params.From || sent > params.To {
+			continue
+		}
+		c.deliver(i, env)
+		count++
+	}
+	return nil
+}
@@ -10,10 +10,18 @@ import (
 // TODO(dshulyak) options must be defined in this module
 type Backend interface {
 	Execute(context.Context, string, []string) error
+	Output(context.Context, string, []string) (string, error)
 	Create(context.Context, string, dockershim.CreateOpts) error
 	Remove(context.Context, string) error
+	RemoveVolume(context.Context, string) error
 	EnsureNetwork(context.Context, dockershim.NetOpts) (string, error)
 	RemoveNetwork(context.Context, string) error
 	ConnectionInfo(context.Context, string, int) ([]nat.PortBinding, error)
 	Reboot(context.Context, string) error
+	Pause(context.Context, string) error
+	Unpause(context.Context, string) error
+	Stop(context.Context, string) error
+	Kill(context.Context, string) error
+	Start(context.Context, string) error
+	ConnectNetwork(context.Context, string, dockershim.IpOpts) error
 }
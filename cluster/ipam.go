@@ -1,47 +1,155 @@
 package cluster
 
 import (
+	"errors"
+	"fmt"
+	"math/big"
 	"net"
 	"sync"
 )
 
+var (
+	ErrIPAMExhausted = errors.New("ipam: no free addresses left")
+)
+
+// reserved number of addresses at the beginning of the network. first is a network address
+// and the second is used by docker as a gateway.
+const reserved = 2
+
 func NewIPAM(cidr string) (*IPAM, error) {
 	_, ipnet, err := net.ParseCIDR(cidr)
 	if err != nil {
 		return nil, err
 	}
+	ones, bits := ipnet.Mask.Size()
+	size := new(big.Int).Lsh(big.NewInt(1), uint(bits-ones))
+	if ipnet.IP.To4() != nil {
+		// broadcast address
+		size.Sub(size, big.NewInt(1))
+	}
+	if size.Cmp(big.NewInt(reserved)) <= 0 {
+		return nil, fmt.Errorf("network %s is too small", cidr)
+	}
 	return &IPAM{
 		cidr:  ipnet,
-		given: 1, // start from 2
-	}, err
+		base:  new(big.Int).SetBytes(ipnet.IP),
+		size:  size,
+		given: big.NewInt(reserved - 1),
+		used:  map[string]struct{}{},
+	}, nil
 }
 
+// IPAM allocates addresses from ipv4 or ipv6 network. Addresses are given sequentially,
+// released addresses are reused before the cursor moves forward.
 type IPAM struct {
 	cidr *net.IPNet
+	base *big.Int
+	// size is a number of addresses that can be used, including reserved
+	size *big.Int
+
+	mu       sync.Mutex
+	given    *big.Int
+	used     map[string]struct{}
+	released []net.IP
+}
 
-	mu    sync.Mutex
-	given byte
+func (i *IPAM) toIP(offset *big.Int) net.IP {
+	n := new(big.Int).Add(i.base, offset)
+	raw := n.Bytes()
+	ip := make(net.IP, len(i.cidr.IP))
+	copy(ip[len(ip)-len(raw):], raw)
+	return ip
 }
 
-// FIXME this will work only for 255 first ips
-func (i *IPAM) Take() net.IP {
+func (i *IPAM) offset(ip net.IP) (*big.Int, error) {
+	if i.cidr.IP.To4() != nil {
+		ip = ip.To4()
+	} else {
+		ip = ip.To16()
+	}
+	if ip == nil || !i.cidr.Contains(ip) {
+		return nil, fmt.Errorf("ip %s is not from %s", ip, i.cidr)
+	}
+	offset := new(big.Int).Sub(new(big.Int).SetBytes(ip), i.base)
+	if offset.Cmp(big.NewInt(reserved)) < 0 || offset.Cmp(i.size) >= 0 {
+		return nil, fmt.Errorf("ip %s is reserved in %s", ip, i.cidr)
+	}
+	return offset, nil
+}
+
+// Take returns next free ip. ErrIPAMExhausted is returned if all addresses are in use.
+func (i *IPAM) Take() (net.IP, error) {
 	i.mu.Lock()
 	defer i.mu.Unlock()
-	new := make(net.IP, 4)
-	copy(new, i.cidr.IP)
-	i.given++
-	new[3] += i.given
-	return new
+	for len(i.released) != 0 {
+		ip := i.released[0]
+		i.released = i.released[1:]
+		if _, exist := i.used[ip.String()]; exist {
+			continue
+		}
+		i.used[ip.String()] = struct{}{}
+		return ip, nil
+	}
+	next := new(big.Int).Set(i.given)
+	one := big.NewInt(1)
+	for {
+		next.Add(next, one)
+		if next.Cmp(i.size) >= 0 {
+			return nil, ErrIPAMExhausted
+		}
+		ip := i.toIP(next)
+		if _, exist := i.used[ip.String()]; exist {
+			continue
+		}
+		i.given = next
+		i.used[ip.String()] = struct{}{}
+		return ip, nil
+	}
 }
 
-// Peek returns current IP
+// Peek returns last given IP
 func (i *IPAM) Peek() net.IP {
 	i.mu.Lock()
 	defer i.mu.Unlock()
-	new := make(net.IP, 4)
-	copy(new, i.cidr.IP)
-	new[3] += i.given
-	return new
+	return i.toIP(i.given)
+}
+
+// Reserve marks ip as taken, so that it won't be returned by Take.
+func (i *IPAM) Reserve(ip net.IP) error {
+	offset, err := i.offset(ip)
+	if err != nil {
+		return err
+	}
+	ip = i.toIP(offset)
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if _, exist := i.used[ip.String()]; exist {
+		return fmt.Errorf("ip %s is already taken", ip)
+	}
+	i.used[ip.String()] = struct{}{}
+	return nil
+}
+
+// Release returns ip to the pool. Released ips are reused before new ones.
+func (i *IPAM) Release(ip net.IP) error {
+	offset, err := i.offset(ip)
+	if err != nil {
+		return err
+	}
+	ip = i.toIP(offset)
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if _, exist := i.used[ip.String()]; !exist {
+		return fmt.Errorf("ip %s is not taken", ip)
+	}
+	delete(i.used, ip.String())
+	i.released = append(i.released, ip)
+	return nil
+}
+
+// IPv6 returns true if ipam allocates ipv6 addresses.
+func (i *IPAM) IPv6() bool {
+	return i.cidr.IP.To4() == nil
 }
 
 func (i *IPAM) String() string {
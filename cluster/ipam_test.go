@@ -0,0 +1,80 @@
+package cluster
+
+import (
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestIPAMExhausted(t *testing.T) {
+	ipam, err := NewIPAM("10.0.0.0/29")
+	require.NoError(t, err)
+	for _, expected := range []string{"10.0.0.2", "10.0.0.3", "10.0.0.4", "10.0.0.5", "10.0.0.6"} {
+		ip, err := ipam.Take()
+		require.NoError(t, err)
+		require.Equal(t, expected, ip.String())
+	}
+	_, err = ipam.Take()
+	require.Equal(t, ErrIPAMExhausted, err)
+}
+
+func TestIPAMLargeNetwork(t *testing.T) {
+	ipam, err := NewIPAM("10.1.0.0/16")
+	require.NoError(t, err)
+	var ip net.IP
+	for i := 0; i < 5000; i++ {
+		ip, err = ipam.Take()
+		require.NoError(t, err)
+	}
+	require.Equal(t, "10.1.19.137", ip.String())
+}
+
+func TestIPAMReleaseReuse(t *testing.T) {
+	ipam, err := NewIPAM("10.0.0.0/24")
+	require.NoError(t, err)
+	first, err := ipam.Take()
+	require.NoError(t, err)
+	_, err = ipam.Take()
+	require.NoError(t, err)
+	require.NoError(t, ipam.Release(first))
+	require.Error(t, ipam.Release(first))
+	ip, err := ipam.Take()
+	require.NoError(t, err)
+	require.Equal(t, first, ip)
+	ip, err = ipam.Take()
+	require.NoError(t, err)
+	require.Equal(t, "10.0.0.4", ip.String())
+}
+
+func TestIPAMReserve(t *testing.T) {
+	ipam, err := NewIPAM("10.0.0.0/24")
+	require.NoError(t, err)
+	require.NoError(t, ipam.Reserve(net.ParseIP("10.0.0.2")))
+	require.Error(t, ipam.Reserve(net.ParseIP("10.0.0.2")))
+	require.Error(t, ipam.Reserve(net.ParseIP("10.0.0.1")))
+	require.Error(t, ipam.Reserve(net.ParseIP("10.0.1.2")))
+	ip, err := ipam.Take()
+	require.NoError(t, err)
+	require.Equal(t, "10.0.0.3", ip.String())
+}
+
+func TestIPAMIPv6(t *testing.T) {
+	ipam, err := NewIPAM("fd00::/120")
+	require.NoError(t, err)
+	require.True(t, ipam.IPv6())
+	ip, err := ipam.Take()
+	require.NoError(t, err)
+	require.Equal(t, "fd00::2", ip.String())
+	for i := 0; i < 253; i++ {
+		ip, err = ipam.Take()
+		require.NoError(t, err)
+	}
+	require.Equal(t, "fd00::ff", ip.String())
+	_, err = ipam.Take()
+	require.Equal(t, ErrIPAMExhausted, err)
+	require.NoError(t, ipam.Release(net.ParseIP("fd00::10")))
+	ip, err = ipam.Take()
+	require.NoError(t, err)
+	require.Equal(t, "fd00::10", ip.String())
+}
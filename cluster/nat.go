@@ -0,0 +1,310 @@
+package cluster
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/ethereum/go-ethereum/log"
+
+	"github.com/status-im/status-scale/dockershim"
+	"github.com/status-im/status-scale/network"
+)
+
+// NATType defines how gateway maps private addresses and ports.
+type NATType string
+
+const (
+	// NATPortRestricted keeps the same public port for a private port, but accepts inbound packets
+	// only from addresses and ports that the private peer sent packets to. This is the default.
+	NATPortRestricted NATType = "port-restricted"
+	// NATSymmetric uses a new random public port for every destination.
+	NATSymmetric NATType = "symmetric"
+
+	// DefaultNATSubnets is used to allocate private networks if Cluster.NATSubnets is empty.
+	DefaultNATSubnets = "172.31.0.0/16"
+)
+
+var (
+	ErrNATIPv6 = errors.New("nat is supported only for ipv4 clusters")
+)
+
+// NATOpts puts users and mvds peers behind nat gateways. Every gateway has its own private network.
+type NATOpts struct {
+	Type NATType
+	// PeersPerGateway is a maximum number of peers behind the gateway. If zero all peers
+	// in a batch share one gateway.
+	PeersPerGateway int
+}
+
+type GatewayConfig struct {
+	Name    string
+	Image   string
+	NetID   string
+	IP      string
+	NATType NATType
+	// Private network of the gateway.
+	PrivateNetID string
+	PrivateIP    string
+	PrivateCIDR  string
+	// Emulator applies network conditions, netem is used if nil.
+	Emulator network.Emulator `json:"-"`
+}
+
+func NewGateway(cfg GatewayConfig, backend Backend) *Gateway {
+	return &Gateway{config: cfg, backend: backend, conditions: newConditions(cfg.Emulator)}
+}
+
+// Gateway is a container that forwards traffic from the private network to the cluster network
+// and masquerades it with its own address. Inbound connections to peers in the private network are dropped.
+type Gateway struct {
+	config     GatewayConfig
+	backend    Backend
+	conditions *conditions
+}
+
+func (g *Gateway) Create(ctx context.Context) error {
+	log.Debug("creating nat gateway", "name", g.config.Name, "private", g.config.PrivateCIDR)
+	err := g.backend.Create(ctx, g.config.Name, dockershim.CreateOpts{
+		Entrypoint: "tail",
+		Cmd:        []string{"-f", "/dev/null"},
+		Image:      g.config.Image,
+		IPs: map[string]dockershim.IpOpts{g.config.NetID: dockershim.IpOpts{
+			IP:    g.config.IP,
+			NetID: g.config.NetID,
+		}},
+		Sysctls: map[string]string{"net.ipv4.ip_forward": "1"},
+	})
+	if err != nil {
+		return err
+	}
+	err = g.backend.ConnectNetwork(ctx, g.config.Name, dockershim.IpOpts{
+		IP:    g.config.PrivateIP,
+		NetID: g.config.PrivateNetID,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to connect %s to private network: %v", g.config.Name, err)
+	}
+	return g.restore(ctx)
+}
+
+// restore applies forwarding rules and network conditions, both are lost when container is restarted.
+func (g *Gateway) restore(ctx context.Context) error {
+	for _, cmd := range g.rules() {
+		if err := g.shell(ctx, cmd); err != nil {
+			return err
+		}
+	}
+	return g.conditions.reapply(ctx, g.shell)
+}
+
+func (g *Gateway) rules() [][]string {
+	cidr := g.config.PrivateCIDR
+	masquerade := []string{"iptables", "-t", "nat", "-A", "POSTROUTING", "-s", cidr, "!", "-d", cidr, "-j", "MASQUERADE"}
+	if g.config.NATType == NATSymmetric {
+		masquerade = append(masquerade, "--random")
+	}
+	return [][]string{
+		masquerade,
+		{"iptables", "-A", "FORWARD", "-d", cidr, "-m", "conntrack", "--ctstate", "ESTABLISHED,RELATED", "-j", "ACCEPT"},
+		{"iptables", "-A", "FORWARD", "-d", cidr, "-j", "DROP"},
+	}
+}
+
+func (g *Gateway) shell(ctx context.Context, cmd []string) error {
+	log.Debug("run command", "gateway", g.config.Name, "command", strings.Join(cmd, " "))
+	return g.backend.Execute(ctx, g.config.Name, cmd)
+}
+
+func (g *Gateway) Remove(ctx context.Context) error {
+	log.Debug("removing nat gateway", "name", g.config.Name)
+	return g.backend.Remove(ctx, g.config.Name)
+}
+
+// removeNetwork removes private network. It fails if some peers are still connected to it.
+func (g *Gateway) removeNetwork(ctx context.Context) error {
+	return g.backend.RemoveNetwork(ctx, g.config.PrivateNetID)
+}
+
+func (g *Gateway) Reboot(ctx context.Context) error {
+	if err := g.backend.Reboot(ctx, g.config.Name); err != nil {
+		return err
+	}
+	return g.restore(ctx)
+}
+
+func (g *Gateway) Pause(ctx context.Context) error {
+	return g.backend.Pause(ctx, g.config.Name)
+}
+
+func (g *Gateway) Unpause(ctx context.Context) error {
+	return g.backend.Unpause(ctx, g.config.Name)
+}
+
+func (g *Gateway) Stop(ctx context.Context) error {
+	return g.backend.Stop(ctx, g.config.Name)
+}
+
+func (g *Gateway) Kill(ctx context.Context) error {
+	return g.backend.Kill(ctx, g.config.Name)
+}
+
+func (g *Gateway) Start(ctx context.Context) error {
+	if err := g.backend.Start(ctx, g.config.Name); err != nil {
+		return err
+	}
+	return g.restore(ctx)
+}
+
+func (g *Gateway) EnableConditions(ctx context.Context, opts ...network.Options) error {
+	return g.conditions.enable(ctx, g.shell, opts...)
+}
+
+func (g *Gateway) DisableConditions(ctx context.Context, opts ...network.Options) error {
+	return g.conditions.disable(ctx, g.shell, opts...)
+}
+
+func (g *Gateway) ActiveConditions() []network.Options {
+	return g.conditions.list()
+}
+
+// IP returns address of the gateway in the cluster network.
+func (g *Gateway) IP() string {
+	return g.config.IP
+}
+
+// PrivateIP returns address of the gateway in its private network.
+func (g *Gateway) PrivateIP() string {
+	return g.config.PrivateIP
+}
+
+func (g *Gateway) UID() string {
+	return g.config.Name
+}
+
+func (g *Gateway) String() string {
+	return fmt.Sprintf("gateway %s %s", g.config.Name, g.config.IP)
+}
+
+func (g *Gateway) Type() PeerType {
+	return NATGateway
+}
+
+// behindNAT is true if node address is not allocated from the cluster network.
+func behindNAT(n Node) bool {
+	switch v := n.(type) {
+	case *Peer:
+		return len(v.config.Gateway) != 0
+	case *Client:
+		return len(v.config.Gateway) != 0
+	}
+	return false
+}
+
+// natSubnet returns i-th /24 subnet of NATSubnets.
+func (c *Cluster) natSubnet(i int) (string, error) {
+	subnets := c.NATSubnets
+	if len(subnets) == 0 {
+		subnets = DefaultNATSubnets
+	}
+	_, ipnet, err := net.ParseCIDR(subnets)
+	if err != nil {
+		return "", err
+	}
+	ones, bits := ipnet.Mask.Size()
+	if bits != 32 || ones > 24 {
+		return "", fmt.Errorf("nat subnets %s must be an ipv4 network with at least /24 prefix", subnets)
+	}
+	if i >= 1<<uint(24-ones) {
+		return "", fmt.Errorf("nat subnets %s are exhausted", subnets)
+	}
+	ip := make(net.IP, net.IPv4len)
+	binary.BigEndian.PutUint32(ip, binary.BigEndian.Uint32(ipnet.IP.To4())+uint32(i)<<8)
+	return ip.String() + "/24", nil
+}
+
+// natGroup assigns peers to gateways. New gateway is created when the current one is full.
+type natGroup struct {
+	cluster *Cluster
+	opts    NATOpts
+	netID   string
+	image   string
+
+	gateway *Gateway
+	ipam    *IPAM
+	count   int
+}
+
+// assign puts the peer behind the gateway.
+func (g *natGroup) assign(ctx context.Context, cfg *PeerConfig) error {
+	if g.gateway == nil || (g.opts.PeersPerGateway != 0 && g.count == g.opts.PeersPerGateway) {
+		if err := g.newGateway(ctx); err != nil {
+			return err
+		}
+	}
+	ip, err := g.ipam.Take()
+	if err != nil {
+		return err
+	}
+	cfg.IP = ip.String()
+	cfg.NetID = g.gateway.config.PrivateNetID
+	cfg.Gateway = g.gateway.PrivateIP()
+	cfg.GatewayRoute = g.cluster.IPAM.String()
+	g.count++
+	return nil
+}
+
+func (g *natGroup) newGateway(ctx context.Context) error {
+	c := g.cluster
+	if c.IPAM.IPv6() {
+		return ErrNATIPv6
+	}
+	i := c.created[NATGateway]
+	cidr, err := c.natSubnet(i)
+	if err != nil {
+		return err
+	}
+	name := c.getName(string(NATGateway), strconv.Itoa(i))
+	ipam, err := NewIPAM(cidr)
+	if err != nil {
+		return err
+	}
+	privateIP, err := ipam.Take()
+	if err != nil {
+		return err
+	}
+	ip, err := c.IPAM.Take()
+	if err != nil {
+		return err
+	}
+	privateNetID, err := c.Backend.EnsureNetwork(ctx, dockershim.NetOpts{
+		NetName: name + "_net",
+		CIDR:    cidr,
+	})
+	if err != nil {
+		if rerr := c.IPAM.Release(ip); rerr != nil {
+			log.Error("failed to release ip", "gateway", name, "error", rerr)
+		}
+		return err
+	}
+	g.gateway = NewGateway(GatewayConfig{
+		Name:         name,
+		Image:        g.image,
+		NetID:        g.netID,
+		IP:           ip.String(),
+		NATType:      g.opts.Type,
+		PrivateNetID: privateNetID,
+		PrivateIP:    privateIP.String(),
+		PrivateCIDR:  cidr,
+		Emulator:     c.Emulator,
+	}, c.Backend)
+	c.pending[NATGateway] = append(c.pending[NATGateway], g.gateway)
+	c.created[NATGateway]++
+	g.ipam = ipam
+	g.count = 0
+	return nil
+}
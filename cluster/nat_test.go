@@ -0,0 +1,26 @@
+package cluster
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestNATSubnet(t *testing.T) {
+	c := &Cluster{}
+	subnet, err := c.natSubnet(0)
+	require.NoError(t, err)
+	require.Equal(t, "172.31.0.0/24", subnet)
+	subnet, err = c.natSubnet(255)
+	require.NoError(t, err)
+	require.Equal(t, "172.31.255.0/24", subnet)
+	_, err = c.natSubnet(256)
+	require.Error(t, err)
+
+	c.NATSubnets = "10.100.4.0/23"
+	subnet, err = c.natSubnet(1)
+	require.NoError(t, err)
+	require.Equal(t, "10.100.5.0/24", subnet)
+	_, err = c.natSubnet(2)
+	require.Error(t, err)
+}
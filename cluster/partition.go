@@ -0,0 +1,112 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/log"
+
+	"github.com/status-im/status-scale/network"
+	"github.com/status-im/status-scale/utils"
+)
+
+var (
+	ErrPartitioned = errors.New("cluster is already partitioned")
+)
+
+// rule is a set of conditions that is enabled on a node by cluster-wide operations.
+type rule struct {
+	node Node
+	opts network.Options
+}
+
+// byNode groups options of rules by node, so that all conditions of a node are enabled with a single call.
+func byNode(rules []rule) (nodes []Node, opts map[string][]network.Options) {
+	opts = map[string][]network.Options{}
+	for _, r := range rules {
+		if _, exist := opts[r.node.UID()]; !exist {
+			nodes = append(nodes, r.node)
+		}
+		opts[r.node.UID()] = append(opts[r.node.UID()], r.opts)
+	}
+	return nodes, opts
+}
+
+func enableRules(ctx context.Context, rules []rule) error {
+	nodes, opts := byNode(rules)
+	group := utils.NewGroup(ctx, len(nodes))
+	for i := range nodes {
+		n := nodes[i]
+		group.Run(func(ctx context.Context) error {
+			return n.EnableConditions(ctx, opts[n.UID()]...)
+		})
+	}
+	return group.Error()
+}
+
+func disableRules(ctx context.Context, rules []rule) error {
+	nodes, opts := byNode(rules)
+	group := utils.NewGroup(ctx, len(nodes))
+	for i := range nodes {
+		n := nodes[i]
+		group.Run(func(ctx context.Context) error {
+			return n.DisableConditions(ctx, opts[n.UID()]...)
+		})
+	}
+	return group.Error()
+}
+
+// Partition drops all traffic between nodes from different groups. Traffic inside of each group
+// and traffic of nodes that are not in any group is not affected.
+func (c *Cluster) Partition(ctx context.Context, groups ...[]Node) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.partition) != 0 {
+		return ErrPartitioned
+	}
+	if len(groups) < 2 {
+		return fmt.Errorf("at least two groups are required, got %d", len(groups))
+	}
+	seen := map[string]int{}
+	for i, group := range groups {
+		for _, n := range group {
+			if j, exist := seen[n.UID()]; exist {
+				return fmt.Errorf("node %s is in groups %d and %d", n.UID(), j, i)
+			}
+			seen[n.UID()] = i
+		}
+	}
+	var rules []rule
+	for i, group := range groups {
+		var others []string
+		for j := range groups {
+			if i == j {
+				continue
+			}
+			for _, n := range groups[j] {
+				others = append(others, n.IP())
+			}
+		}
+		for _, n := range group {
+			rules = append(rules, rule{node: n, opts: network.Options{
+				PacketLoss:  100,
+				TargetAddrs: others,
+			}})
+		}
+	}
+	log.Info("partitioning cluster", "groups", len(groups), "nodes", len(rules))
+	// heal will try to remove rules from every node even if some of them failed
+	c.partition = rules
+	return enableRules(ctx, rules)
+}
+
+// Heal removes partition that was created with Partition.
+func (c *Cluster) Heal(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	log.Info("healing cluster", "nodes", len(c.partition))
+	rules := c.partition
+	c.partition = nil
+	return disableRules(ctx, rules)
+}
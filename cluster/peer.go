@@ -42,6 +42,7 @@ func DefaultConfig() PeerConfig {
 const (
 	tmpSuffix       = "scale-peer-%s-"
 	containerConfig = "/conf.json"
+	dataDir         = "/status-data"
 
 	DefaultMailServerPassword = "status-offline-inbox"
 )
@@ -70,30 +71,55 @@ type Client struct {
 }
 
 func NewPeer(config PeerConfig, backend Backend, cmd []string) *Peer {
-	return &Peer{baseCmd: cmd, name: config.Name, config: config, backend: backend}
+	if len(config.NodeKey) == 0 {
+		key, err := crypto.GenerateKey() // it can fail only if rand.Reader will return err on read all
+		if err != nil {
+			panic(err)
+		}
+		config.NodeKey = hex.EncodeToString(crypto.FromECDSA(key))
+	}
+	return &Peer{baseCmd: cmd, name: config.Name, config: config, backend: backend, conditions: newConditions(config.Emulator)}
 }
 
 type PeerConfig struct {
+	Type  PeerType
 	Name  string
 	NetID string
 	IP    string
 	Image string
+	// NodeKey is a hex encoded p2p key. Peer keeps the same enode when container is recreated.
+	NodeKey string
+
+	Mailserver         bool
+	MailServerPassword string
+	Modules            []string
+	Whisper            bool
+	BootNodes          []string
+	RendezvousNodes    []string
+	Mailservers        []string
+	NetworkID          int
+	HTTP               bool
+	Port               int
+	Host               string
+	Metrics            bool
+	TopicSearch        map[string]string
+	TopicRegister      []string
+	Discovery          bool
+	Standalone         bool
 
-	Mailserver      bool
-	Modules         []string
-	Whisper         bool
-	BootNodes       []string
-	RendezvousNodes []string
-	Mailservers     []string
-	NetworkID       int
-	HTTP            bool
-	Port            int
-	Host            string
-	Metrics         bool
-	TopicSearch     map[string]string
-	TopicRegister   []string
-	Discovery       bool
-	Standalone      bool
+	// Gateway is an address of nat gateway in the private network of the peer.
+	// Traffic to GatewayRoute is routed through it.
+	Gateway      string `json:",omitempty"`
+	GatewayRoute string `json:",omitempty"`
+
+	// NodeConfigPatch is a json object that is merged into generated status-go config,
+	// e.g. {"MaxPeers": 10, "WhisperConfig": {"MinimumPoW": 0.002}}.
+	NodeConfigPatch json.RawMessage `json:",omitempty"`
+	// NodeConfigHook changes generated status-go config after NodeConfigPatch is merged.
+	// It is not persisted with cluster state, attached peers are never recreated with it.
+	NodeConfigHook func(*params.NodeConfig) `json:"-"`
+	// Emulator applies network conditions, netem is used if nil.
+	Emulator network.Emulator `json:"-"`
 }
 
 type Peer struct {
@@ -102,7 +128,8 @@ type Peer struct {
 	name    string
 	config  PeerConfig
 
-	backend Backend
+	backend    Backend
+	conditions *conditions
 
 	client *rpc.Client
 	enode  string
@@ -121,10 +148,11 @@ func (p *Peer) Enode() string {
 func (p *Peer) Create(ctx context.Context) error {
 	cmd := make([]string, len(p.baseCmd))
 	copy(cmd, p.baseCmd)
-	cfg, err := params.NewNodeConfig("/status-data", 7777)
+	cfg, err := params.NewNodeConfig(dataDir, 7777)
 	if err != nil {
 		return err
 	}
+	cfg.NodeKey = p.config.NodeKey
 	cfg.LogEnabled = true
 	cfg.LogToStderr = true
 	cfg.LogLevel = "INFO"
@@ -135,6 +163,9 @@ func (p *Peer) Create(ctx context.Context) error {
 			cfg.WhisperConfig.EnableMailServer = true
 			cfg.WhisperConfig.DataDir = filepath.Join(cfg.DataDir, "mail")
 			cfg.WhisperConfig.MailServerPassword = DefaultMailServerPassword
+			if len(p.config.MailServerPassword) != 0 {
+				cfg.WhisperConfig.MailServerPassword = p.config.MailServerPassword
+			}
 		}
 	}
 	if p.config.HTTP {
@@ -152,11 +183,12 @@ func (p *Peer) Create(ctx context.Context) error {
 	}
 	cfg.ClusterConfig.Enabled = true
 	cfg.NoDiscovery = true
-	if len(p.config.BootNodes) != 0 {
+	// without discovery peers are connected only with admin_addPeer
+	if p.config.Discovery && len(p.config.BootNodes) != 0 {
 		cfg.NoDiscovery = false
 		cfg.ClusterConfig.BootNodes = p.config.BootNodes
 	}
-	if len(p.config.RendezvousNodes) != 0 {
+	if p.config.Discovery && len(p.config.RendezvousNodes) != 0 {
 		cfg.Rendezvous = true
 		cfg.ClusterConfig.RendezvousNodes = p.config.RendezvousNodes
 	}
@@ -165,12 +197,21 @@ func (p *Peer) Create(ctx context.Context) error {
 		cfg.RegisterTopics = append(cfg.RegisterTopics, discv5.Topic(topic))
 	}
 	for topic, args := range p.config.TopicSearch {
-		limits := strings.Split(args, ",")
-		min, _ := strconv.Atoi(limits[0])
-		max, _ := strconv.Atoi(limits[1])
-		cfg.RequireTopics[discv5.Topic(topic)] = params.Limits{Min: min, Max: max}
+		limits, err := parseLimits(args)
+		if err != nil {
+			return fmt.Errorf("invalid search for topic %s: %v", topic, err)
+		}
+		cfg.RequireTopics[discv5.Topic(topic)] = limits
+	}
+	cfg.ListenAddr = net.JoinHostPort(p.IP(), "30303")
+	if len(p.config.NodeConfigPatch) != 0 {
+		if err := json.Unmarshal(p.config.NodeConfigPatch, cfg); err != nil {
+			return fmt.Errorf("failed to merge config patch: %v", err)
+		}
+	}
+	if p.config.NodeConfigHook != nil {
+		p.config.NodeConfigHook(cfg)
 	}
-	cfg.ListenAddr = fmt.Sprintf("%s:30303", p.IP())
 	log.Debug("Create statusd", "name", p.name, "command", strings.Join(cmd, " "))
 	bytes, err := json.Marshal(cfg)
 	if err != nil {
@@ -196,18 +237,41 @@ func (p *Peer) Create(ctx context.Context) error {
 		}},
 		HostConfigPath:      p.hostConfig,
 		ContainerConfigPath: containerConfig,
+		Volumes:             map[string]string{p.volume(): dataDir},
 	})
 	if err != nil {
 		return err
 	}
-	p.client, err = p.makeRPCClient(ctx)
+	// conditions that were enabled before the container was recreated, e.g. by upgrade, are applied again
+	return p.restore(ctx)
+}
+
+// Remove removes the container and its data volume.
+// parseLimits parses min and max number of peers for a topic search, e.g. "2,5".
+func parseLimits(args string) (params.Limits, error) {
+	parts := strings.Split(args, ",")
+	if len(parts) != 2 {
+		return params.Limits{}, fmt.Errorf("topic search must be min,max, got %q", args)
+	}
+	min, err := strconv.Atoi(strings.TrimSpace(parts[0]))
 	if err != nil {
-		return err
+		return params.Limits{}, fmt.Errorf("topic search must be min,max, got %q", args)
 	}
-	return p.healthcheck(ctx, 20, time.Second)
+	max, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+	if err != nil || min > max {
+		return params.Limits{}, fmt.Errorf("topic search must be min,max, got %q", args)
+	}
+	return params.Limits{Min: min, Max: max}, nil
 }
 
 func (p *Peer) Remove(ctx context.Context) error {
+	if err := p.removeContainer(ctx); err != nil {
+		return err
+	}
+	return p.backend.RemoveVolume(ctx, p.volume())
+}
+
+func (p *Peer) removeContainer(ctx context.Context) error {
 	log.Debug("removing statusd", "name", p.name)
 	if len(p.hostConfig) > 0 {
 		if err := os.Remove(p.hostConfig); err != nil {
@@ -217,24 +281,44 @@ func (p *Peer) Remove(ctx context.Context) error {
 	return p.backend.Remove(ctx, p.name)
 }
 
-func (p *Peer) EnableConditions(ctx context.Context, opt network.Options) error {
-	return network.ComcastStart(func(ctx context.Context, cmd []string) error {
-		log.Debug("run command", "peer", p.name, "command", strings.Join(cmd, " "))
-		return p.backend.Execute(ctx, p.name, cmd)
-	}, ctx, opt)
+// recreate replaces the container with a container from the image. Data volume and network conditions are kept.
+func (p *Peer) recreate(ctx context.Context, image string) error {
+	if err := p.removeContainer(ctx); err != nil {
+		return err
+	}
+	p.config.Image = image
+	return p.Create(ctx)
+}
+
+func (p *Peer) volume() string {
+	return p.name + "_data"
 }
 
-func (p *Peer) DisableConditions(ctx context.Context, opt network.Options) error {
-	err := network.ComcastStop(func(ctx context.Context, cmd []string) error {
-		log.Debug("run command", "peer", p.name, "command", strings.Join(cmd, " "))
-		return p.backend.Execute(ctx, p.name, cmd)
-	}, ctx, opt)
-	if err != nil {
-		return fmt.Errorf("failed to stop comcast on a peer %s: %v", p.name, err)
+func (p *Peer) shell(ctx context.Context, cmd []string) error {
+	log.Debug("run command", "peer", p.name, "command", strings.Join(cmd, " "))
+	return p.backend.Execute(ctx, p.name, cmd)
+}
+
+func (p *Peer) EnableConditions(ctx context.Context, opts ...network.Options) error {
+	return p.conditions.enable(ctx, p.shell, opts...)
+}
+
+func (p *Peer) DisableConditions(ctx context.Context, opts ...network.Options) error {
+	if err := p.conditions.disable(ctx, p.shell, opts...); err != nil {
+		return fmt.Errorf("failed to disable conditions on a peer %s: %v", p.name, err)
 	}
 	return nil
 }
 
+// ActiveConditions returns network conditions that are enabled on the peer, in the order in which they were enabled.
+func (p *Peer) ActiveConditions() []network.Options {
+	return p.conditions.list()
+}
+
+func (p *Peer) Type() PeerType {
+	return p.config.Type
+}
+
 func (p *Peer) IP() string {
 	return p.config.IP
 }
@@ -289,22 +373,77 @@ func (p *Peer) healthcheck(ctx context.Context, retries int, interval time.Durat
 		if err != nil {
 			return err
 		}
-		parts := strings.Split(info.ListenAddr, ":")
-		if len(parts) != 2 {
-			return fmt.Errorf("failed to split %s into two parts uins `:`", info.ListenAddr)
+		host, _, err := net.SplitHostPort(info.ListenAddr)
+		if err != nil {
+			return fmt.Errorf("failed to split %s into host and port: %v", info.ListenAddr, err)
 		}
-		p.enode = enode.NewV4(node.Pubkey(), net.ParseIP(parts[0]), info.Ports.Listener, info.Ports.Discovery).String()
+		p.enode = enode.NewV4(node.Pubkey(), net.ParseIP(host), info.Ports.Listener, info.Ports.Discovery).String()
 		log.Debug("received enode for", "name", p.name, "enode", p.enode)
 		return nil
 	}
 	return fmt.Errorf("peer %s failed healthcheck", p.name)
 }
 
+// attach connects to a peer that was created by another process.
+func (p *Peer) attach(ctx context.Context) (err error) {
+	p.client, err = p.makeRPCClient(ctx)
+	if err != nil {
+		return err
+	}
+	return p.healthcheck(ctx, 3, time.Second)
+}
+
 func (p *Peer) Reboot(ctx context.Context) (err error) {
 	log.Debug("reboot", "peer", p.name)
 	if err = p.backend.Reboot(ctx, p.name); err != nil {
 		return err
 	}
+	return p.restore(ctx)
+}
+
+// Pause freezes the peer. Its connections stay open, but nothing is received or sent.
+func (p *Peer) Pause(ctx context.Context) error {
+	log.Debug("pause", "peer", p.name)
+	return p.backend.Pause(ctx, p.name)
+}
+
+func (p *Peer) Unpause(ctx context.Context) error {
+	log.Debug("unpause", "peer", p.name)
+	return p.backend.Unpause(ctx, p.name)
+}
+
+// Stop gracefully stops the peer. Data is kept and peer can be started again.
+func (p *Peer) Stop(ctx context.Context) error {
+	log.Debug("stop", "peer", p.name)
+	return p.backend.Stop(ctx, p.name)
+}
+
+// Kill stops the peer without giving it a chance to clean up. Data is kept and peer can be started again.
+func (p *Peer) Kill(ctx context.Context) error {
+	log.Debug("kill", "peer", p.name)
+	return p.backend.Kill(ctx, p.name)
+}
+
+// Start starts the peer after Stop or Kill.
+func (p *Peer) Start(ctx context.Context) error {
+	log.Debug("start", "peer", p.name)
+	if err := p.backend.Start(ctx, p.name); err != nil {
+		return err
+	}
+	return p.restore(ctx)
+}
+
+// restore recovers state that is lost when container is restarted: route through the gateway,
+// network conditions and rpc client.
+func (p *Peer) restore(ctx context.Context) (err error) {
+	if len(p.config.Gateway) != 0 {
+		if err := p.shell(ctx, []string{"ip", "route", "add", p.config.GatewayRoute, "via", p.config.Gateway}); err != nil {
+			return fmt.Errorf("failed to add route through gateway: %v", err)
+		}
+	}
+	if err := p.conditions.reapply(ctx, p.shell); err != nil {
+		return fmt.Errorf("failed to restore network conditions: %v", err)
+	}
 	p.client, err = p.makeRPCClient(ctx)
 	if err != nil {
 		return err
@@ -0,0 +1,63 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+
+	"github.com/status-im/status-scale/network"
+)
+
+const (
+	// phaseCleanupTimeout is used to remove conditions of the last phase after replay was cancelled.
+	phaseCleanupTimeout = 10 * time.Second
+)
+
+// Replay applies phases of the profile to the node one after another, until profile is finished
+// or context is cancelled. Conditions of the current phase are removed before Replay returns.
+// Conditions that were enabled on the node by other means are not affected.
+func Replay(ctx context.Context, n Node, profile network.Profile) error {
+	if err := profile.Validate(); err != nil {
+		return err
+	}
+	for {
+		for i, phase := range profile.Phases {
+			log.Debug("replaying phase", "node", n.UID(), "phase", i, "duration", phase.Duration)
+			if err := replayPhase(ctx, n, phase); err != nil {
+				return fmt.Errorf("phase %d failed on %s: %v", i, n.UID(), err)
+			}
+			if ctx.Err() != nil {
+				return nil
+			}
+		}
+		if !profile.Loop {
+			return nil
+		}
+	}
+}
+
+func replayPhase(ctx context.Context, n Node, phase network.Phase) error {
+	if !phase.Options.Empty() {
+		if err := n.EnableConditions(ctx, phase.Options); err != nil {
+			return err
+		}
+	}
+	var timeout <-chan time.Time
+	if phase.Duration != 0 {
+		timer := time.NewTimer(phase.Duration)
+		defer timer.Stop()
+		timeout = timer.C
+	}
+	select {
+	case <-timeout:
+	case <-ctx.Done():
+	}
+	if phase.Options.Empty() {
+		return nil
+	}
+	cleanup, cancel := context.WithTimeout(context.Background(), phaseCleanupTimeout)
+	defer cancel()
+	return n.DisableConditions(cleanup, phase.Options)
+}
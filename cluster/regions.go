@@ -0,0 +1,79 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+
+	"github.com/ethereum/go-ethereum/log"
+
+	"github.com/status-im/status-scale/network"
+)
+
+var (
+	ErrRegionsApplied = errors.New("regions are already applied")
+)
+
+// ApplyRegions delays traffic between nodes according to their regions. Half of the rtt from the matrix
+// is applied on each side of the link, including links between nodes in the same region.
+// Nodes that are not in any region are not affected.
+func (c *Cluster) ApplyRegions(ctx context.Context, matrix network.LatencyMatrix, regions map[string][]Node) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.regions) != 0 {
+		return ErrRegionsApplied
+	}
+	if err := matrix.Validate(); err != nil {
+		return err
+	}
+	names := make([]string, 0, len(regions))
+	seen := map[string]string{}
+	for name, nodes := range regions {
+		names = append(names, name)
+		for _, n := range nodes {
+			if other, exist := seen[n.UID()]; exist {
+				return fmt.Errorf("node %s is in regions %s and %s", n.UID(), other, name)
+			}
+			seen[n.UID()] = name
+		}
+	}
+	sort.Strings(names)
+	var rules []rule
+	for _, from := range names {
+		for _, n := range regions[from] {
+			for _, to := range names {
+				rtt, exist := matrix.RTT(from, to)
+				if !exist || rtt/2 == 0 {
+					continue
+				}
+				var addrs []string
+				for _, other := range regions[to] {
+					if other.UID() != n.UID() {
+						addrs = append(addrs, other.IP())
+					}
+				}
+				if len(addrs) == 0 {
+					continue
+				}
+				rules = append(rules, rule{node: n, opts: network.Options{
+					Latency:     rtt / 2,
+					TargetAddrs: addrs,
+				}})
+			}
+		}
+	}
+	log.Info("applying regions", "regions", len(regions), "rules", len(rules))
+	c.regions = rules
+	return enableRules(ctx, rules)
+}
+
+// ClearRegions removes delays that were applied with ApplyRegions.
+func (c *Cluster) ClearRegions(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	log.Info("clearing regions", "rules", len(c.regions))
+	rules := c.regions
+	c.regions = nil
+	return disableRules(ctx, rules)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/hex"
 	"fmt"
+	"net"
 	"strconv"
 	"strings"
 
@@ -16,7 +17,18 @@ import (
 	"github.com/status-im/status-scale/dockershim"
 )
 
-type Rendezvous Bootnode
+func NewRendezvous(cfg BootnodeConfig, backend Backend) Rendezvous {
+	return Rendezvous{NewBootnode(cfg, backend)}
+}
+
+// Rendezvous reuses bootnode for everything except create.
+type Rendezvous struct {
+	Bootnode
+}
+
+func (r Rendezvous) Type() PeerType {
+	return RendezvousBoot
+}
 
 func (r Rendezvous) String() string {
 	return fmt.Sprintf("rendezvous %s: %s", r.name, r.Addr())
@@ -26,7 +38,7 @@ func (r Rendezvous) Create(ctx context.Context) error {
 	data := hex.EncodeToString(crypto.FromECDSA(r.key))
 	cmd := []string{"-a=" + r.ip, "-p=" + strconv.Itoa(r.port), "--keyhex=" + data}
 	log.Debug("creating rendezvous", "name", r.name, "address", r.String(), "cmd", strings.Join(cmd, " "))
-	return r.backend.Create(ctx, r.name, dockershim.CreateOpts{
+	err := r.backend.Create(ctx, r.name, dockershim.CreateOpts{
 		Entrypoint: "rendezvous",
 		Cmd:        cmd,
 		Image:      r.image,
@@ -36,6 +48,10 @@ func (r Rendezvous) Create(ctx context.Context) error {
 		}},
 	},
 	)
+	if err != nil {
+		return err
+	}
+	return r.conditions.reapply(ctx, r.shell)
 }
 
 func (r Rendezvous) Addr() string {
@@ -45,7 +61,11 @@ func (r Rendezvous) Addr() string {
 		log.Error("unable to convert public key to pid", "error", err)
 		return ""
 	}
-	return fmt.Sprintf("/ip4/%s/tcp/%d/ethv4/%s", r.ip, r.port, id.Pretty())
+	proto := "ip4"
+	if ip := net.ParseIP(r.ip); ip != nil && ip.To4() == nil {
+		proto = "ip6"
+	}
+	return fmt.Sprintf("/%s/%s/tcp/%d/ethv4/%s", proto, r.ip, r.port, id.Pretty())
 }
 
 func (r Rendezvous) Remove(ctx context.Context) error {
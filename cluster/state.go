@@ -0,0 +1,222 @@
+package cluster
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"path/filepath"
+
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+
+	"github.com/status-im/status-scale/utils"
+)
+
+// nodeState is enough to rebuild a node that is already running in a container.
+type nodeState struct {
+	Peer       *PeerConfig     `json:",omitempty"`
+	Bootnode   *BootnodeConfig `json:",omitempty"`
+	Gateway    *GatewayConfig  `json:",omitempty"`
+	Cmd        []string        `json:",omitempty"`
+	HostConfig string          `json:",omitempty"`
+	// Key is a hex encoded private key of a bootnode or an identity of a client.
+	Key string `json:",omitempty"`
+}
+
+type clusterState struct {
+	NetID   string
+	Created map[PeerType]int
+	Running map[PeerType][]nodeState
+}
+
+func (c *Cluster) statePath() string {
+	return filepath.Join(c.RunDir, c.Prefix+".json")
+}
+
+func encodeKey(key *ecdsa.PrivateKey) string {
+	return hex.EncodeToString(crypto.FromECDSA(key))
+}
+
+func stateOf(n Node) (nodeState, error) {
+	switch v := n.(type) {
+	case *Peer:
+		return nodeState{Peer: &v.config, Cmd: v.baseCmd, HostConfig: v.hostConfig}, nil
+	case *Client:
+		return nodeState{Peer: &v.config, Cmd: v.baseCmd, HostConfig: v.hostConfig, Key: encodeKey(v.Identity)}, nil
+	case Bootnode:
+		return nodeState{Bootnode: v.config(), Key: encodeKey(v.key)}, nil
+	case Rendezvous:
+		return nodeState{Bootnode: v.config(), Key: encodeKey(v.key)}, nil
+	case *Gateway:
+		return nodeState{Gateway: &v.config}, nil
+	}
+	return nodeState{}, fmt.Errorf("can't save state of %v", n)
+}
+
+func (c *Cluster) nodeFromState(typ PeerType, s nodeState) (Node, error) {
+	var (
+		key *ecdsa.PrivateKey
+		err error
+	)
+	if len(s.Key) != 0 {
+		key, err = crypto.HexToECDSA(s.Key)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if s.Peer != nil {
+		s.Peer.Emulator = c.Emulator
+	}
+	switch typ {
+	case Boot, RendezvousBoot:
+		if s.Bootnode == nil || key == nil {
+			return nil, fmt.Errorf("bootnode config and key are required for %s", typ)
+		}
+		s.Bootnode.Emulator = c.Emulator
+		b := NewBootnode(*s.Bootnode, c.Backend)
+		b.key = key
+		if typ == RendezvousBoot {
+			return Rendezvous{b}, nil
+		}
+		return b, nil
+	case NATGateway:
+		if s.Gateway == nil {
+			return nil, fmt.Errorf("gateway config is required for %s", typ)
+		}
+		s.Gateway.Emulator = c.Emulator
+		return NewGateway(*s.Gateway, c.Backend), nil
+	case Relay, Mail:
+		if s.Peer == nil {
+			return nil, fmt.Errorf("peer config is required for %s", typ)
+		}
+		p := NewPeer(*s.Peer, c.Backend, s.Cmd)
+		p.hostConfig = s.HostConfig
+		return p, nil
+	case User, MVDS:
+		if s.Peer == nil || key == nil {
+			return nil, fmt.Errorf("peer config and identity are required for %s", typ)
+		}
+		p := NewPeer(*s.Peer, c.Backend, s.Cmd)
+		p.hostConfig = s.HostConfig
+		return &Client{Peer: p, Identity: key}, nil
+	}
+	return nil, fmt.Errorf("unknown peer type %s", typ)
+}
+
+// save persists state of running nodes to the run directory. Must be called with mutex held.
+func (c *Cluster) save() error {
+	if len(c.RunDir) == 0 {
+		return nil
+	}
+	state := clusterState{
+		NetID:   c.netID,
+		Created: c.created,
+		Running: map[PeerType][]nodeState{},
+	}
+	for typ, nodes := range c.running {
+		for _, n := range nodes {
+			s, err := stateOf(n)
+			if err != nil {
+				return err
+			}
+			state.Running[typ] = append(state.Running[typ], s)
+		}
+	}
+	data, err := json.MarshalIndent(state, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(c.RunDir, 0755); err != nil {
+		return err
+	}
+	return ioutil.WriteFile(c.statePath(), data, 0644)
+}
+
+// Attach loads state saved by the cluster with the same prefix and connects to running containers.
+// Cluster must be empty. Attached peers are counted as created by the following calls to Create.
+// Cluster and its addresses are changed only if every node was loaded and attached.
+func (c *Cluster) Attach(ctx context.Context) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.RunDir) == 0 {
+		return fmt.Errorf("run directory is not set")
+	}
+	if len(c.nodes()) != 0 {
+		return fmt.Errorf("cluster %s already has running peers", c.Prefix)
+	}
+	data, err := ioutil.ReadFile(c.statePath())
+	if err != nil {
+		return fmt.Errorf("failed to read state of %s: %v", c.Prefix, err)
+	}
+	var state clusterState
+	if err := json.Unmarshal(data, &state); err != nil {
+		return fmt.Errorf("failed to decode state %s: %v", c.statePath(), err)
+	}
+	log.Info("attaching to cluster", "prefix", c.Prefix, "state", c.statePath())
+	running := map[PeerType][]Node{}
+	var peers []rpcNode
+	for typ, states := range state.Running {
+		for _, s := range states {
+			n, err := c.nodeFromState(typ, s)
+			if err != nil {
+				return err
+			}
+			running[typ] = append(running[typ], n)
+			if p, ok := n.(rpcNode); ok {
+				peers = append(peers, p)
+			}
+		}
+	}
+	group := utils.NewGroup(ctx, len(peers))
+	for i := range peers {
+		p := peers[i]
+		group.Run(func(ctx context.Context) error {
+			if err := p.attach(ctx); err != nil {
+				return fmt.Errorf("error attaching to %v: %v", p, err)
+			}
+			return nil
+		})
+	}
+	if err := group.Error(); err != nil {
+		return err
+	}
+	var reserved []net.IP
+	for _, nodes := range running {
+		for _, n := range nodes {
+			if behindNAT(n) {
+				continue
+			}
+			ip := net.ParseIP(n.IP())
+			if err := c.IPAM.Reserve(ip); err != nil {
+				for _, ip := range reserved {
+					if rerr := c.IPAM.Release(ip); rerr != nil {
+						log.Error("failed to release ip", "ip", ip, "error", rerr)
+					}
+				}
+				return fmt.Errorf("failed to reserve address of %v: %v", n, err)
+			}
+			reserved = append(reserved, ip)
+		}
+	}
+	c.netID = state.NetID
+	c.running = running
+	c.attached = map[PeerType]int{}
+	for typ, nodes := range running {
+		c.attached[typ] = len(nodes)
+	}
+	if state.Created != nil {
+		c.created = state.Created
+	}
+	return nil
+}
+
+// rpcNode is implemented by nodes that can be reached with rpc.
+type rpcNode interface {
+	Node
+	attach(context.Context) error
+}
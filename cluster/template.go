@@ -0,0 +1,67 @@
+package cluster
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// Template changes config of a status-go peer before it is created.
+// Template must not change Name, IP, NetID or Type, they are managed by the cluster.
+type Template func(*PeerConfig)
+
+// DefaultTemplates are applied to every peer of the type before templates from ScaleOpts.
+var DefaultTemplates = map[PeerType]Template{
+	Mail: func(cfg *PeerConfig) {
+		cfg.Mailserver = true
+		cfg.MailServerPassword = DefaultMailServerPassword
+		cfg.TopicSearch = map[string]string{"whisper": "3,5"}
+		cfg.TopicRegister = []string{"mail"}
+	},
+	Relay: func(cfg *PeerConfig) {
+		cfg.TopicSearch = map[string]string{"whisper": "5,7"}
+		cfg.TopicRegister = []string{"whisper"}
+	},
+	User: func(cfg *PeerConfig) {
+		cfg.TopicSearch = map[string]string{"whisper": "2,2"}
+	},
+	MVDS: func(cfg *PeerConfig) {
+		cfg.TopicSearch = map[string]string{"whisper": "2,2"}
+	},
+}
+
+// Patch returns template that unmarshals json object on top of the config.
+// Fields that are not in the object are left unchanged, e.g. {"TopicSearch": {"whisper": "1,3"}}.
+// Unknown fields are rejected.
+func Patch(patch []byte) (Template, error) {
+	// validate patch once, so that template itself can't fail
+	dec := json.NewDecoder(bytes.NewReader(patch))
+	dec.DisallowUnknownFields()
+	var cfg PeerConfig
+	if err := dec.Decode(&cfg); err != nil {
+		return nil, fmt.Errorf("invalid config patch: %v", err)
+	}
+	for topic, args := range cfg.TopicSearch {
+		if _, err := parseLimits(args); err != nil {
+			return nil, fmt.Errorf("invalid config patch: search for topic %s: %v", topic, err)
+		}
+	}
+	return func(cfg *PeerConfig) {
+		_ = json.Unmarshal(patch, cfg)
+	}, nil
+}
+
+// Chain applies templates in order.
+func Chain(templates ...Template) Template {
+	return func(cfg *PeerConfig) {
+		for _, t := range templates {
+			if t != nil {
+				t(cfg)
+			}
+		}
+	}
+}
+
+func (opts ScaleOpts) apply(typ PeerType, cfg *PeerConfig) {
+	Chain(DefaultTemplates[typ], opts.Templates[typ])(cfg)
+}
@@ -0,0 +1,46 @@
+package cluster
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/status-im/status-scale/cluster/fakebackend"
+)
+
+func TestTemplates(t *testing.T) {
+	patch, err := Patch([]byte(`{"TopicSearch": {"whisper": "1,3"}, "Modules": ["shh"]}`))
+	require.NoError(t, err)
+	opts := ScaleOpts{Templates: map[PeerType]Template{Relay: patch}}
+
+	cfg := DefaultConfig()
+	opts.apply(Relay, &cfg)
+	require.Equal(t, map[string]string{"whisper": "1,3"}, cfg.TopicSearch)
+	require.Equal(t, []string{"shh"}, cfg.Modules)
+	require.Equal(t, []string{"whisper"}, cfg.TopicRegister)
+
+	cfg = DefaultConfig()
+	opts.apply(Mail, &cfg)
+	require.True(t, cfg.Mailserver)
+	require.Equal(t, map[string]string{"whisper": "3,5"}, cfg.TopicSearch)
+
+	_, err = Patch([]byte(`{"Port": "x"}`))
+	require.Error(t, err)
+	_, err = Patch([]byte(`{"TopicSerch": {"whisper": "1,3"}}`))
+	require.Error(t, err)
+	for _, limits := range []string{"5", "a,3", "1,b", "3,1"} {
+		_, err = Patch([]byte(`{"TopicSearch": {"whisper": "` + limits + `"}}`))
+		require.Error(t, err, limits)
+		require.Contains(t, err.Error(), "topic search must be min,max")
+	}
+
+	// templates that are not patches are validated when peer is created
+	backend := fakebackend.New()
+	defer backend.Close()
+	cfg = DefaultConfig()
+	cfg.TopicSearch = map[string]string{"whisper": "5"}
+	err = NewStatusd(cfg, backend).Create(context.TODO())
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "topic search must be min,max")
+}
@@ -0,0 +1,116 @@
+package cluster
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/log"
+
+	"github.com/status-im/status-scale/utils"
+)
+
+// Selector picks nodes for cluster-wide operations.
+type Selector func(Node) bool
+
+// ByType selects nodes of the given types.
+func ByType(types ...PeerType) Selector {
+	return func(n Node) bool {
+		for _, typ := range types {
+			if n.Type() == typ {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+func withImage(n Node, image string) (Node, error) {
+	switch v := n.(type) {
+	case Bootnode:
+		v.image = image
+		return v, nil
+	case Rendezvous:
+		v.image = image
+		return v, nil
+	}
+	return nil, fmt.Errorf("can't change image of %v", n)
+}
+
+// Upgrade recreates selected nodes with a new image in batches of batchSize.
+// Recreated nodes keep their name, ip, keys, data and network conditions. Next batch is started only after
+// every node in the current batch was created and passed healthcheck.
+func (c *Cluster) Upgrade(ctx context.Context, selector Selector, image string, batchSize int) error {
+	if batchSize <= 0 {
+		return fmt.Errorf("batch size must be positive, got %d", batchSize)
+	}
+	c.mu.Lock()
+	var selected []Node
+	for _, n := range c.nodes() {
+		if selector(n) {
+			selected = append(selected, n)
+		}
+	}
+	c.mu.Unlock()
+	log.Info("upgrading nodes", "image", image, "nodes", len(selected), "batch", batchSize)
+	for start := 0; start < len(selected); start += batchSize {
+		end := start + batchSize
+		if end > len(selected) {
+			end = len(selected)
+		}
+		batch := selected[start:end]
+		group := utils.NewGroup(ctx, len(batch))
+		for i := range batch {
+			i := i
+			group.Run(func(ctx context.Context) error {
+				n, err := c.upgrade(ctx, batch[i], image)
+				if err != nil {
+					return fmt.Errorf("error upgrading %v: %v", batch[i], err)
+				}
+				batch[i] = n
+				return nil
+			})
+		}
+		err := group.Error()
+		c.mu.Lock()
+		for _, n := range batch {
+			c.replace(n)
+		}
+		if serr := c.save(); serr != nil {
+			log.Error("failed to save cluster state", "error", serr)
+		}
+		c.mu.Unlock()
+		if err != nil {
+			return err
+		}
+		log.Debug("upgraded batch", "from", start, "to", end)
+	}
+	return nil
+}
+
+func (c *Cluster) upgrade(ctx context.Context, n Node, image string) (Node, error) {
+	switch v := n.(type) {
+	case *Peer:
+		return v, v.recreate(ctx, image)
+	case *Client:
+		return v, v.recreate(ctx, image)
+	}
+	if err := n.Remove(ctx); err != nil {
+		return n, err
+	}
+	updated, err := withImage(n, image)
+	if err != nil {
+		return n, err
+	}
+	return updated, updated.Create(ctx)
+}
+
+// replace swaps running node with the same uid.
+func (c *Cluster) replace(n Node) {
+	nodes := c.running[n.Type()]
+	for i := range nodes {
+		if nodes[i].UID() == n.UID() {
+			nodes[i] = n
+			return
+		}
+	}
+}
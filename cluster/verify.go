@@ -0,0 +1,133 @@
+package cluster
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/ethereum/go-ethereum/log"
+
+	"github.com/status-im/status-scale/network"
+)
+
+const (
+	verifyPings = 10
+	// verifyTransfer is an expected duration of the throughput sample.
+	verifyTransfer = 4 * time.Second
+)
+
+var (
+	ErrNoVerifyTarget = errors.New("no node without conditions towards the node in target addrs")
+)
+
+// Verification compares conditions that were requested on a node with values measured
+// between the node and a target.
+type Verification struct {
+	Node       string
+	Target     string
+	Requested  network.Measurement
+	Measured   network.Measurement
+	Violations []string
+}
+
+func (v Verification) String() string {
+	return fmt.Sprintf("%s -> %s: requested %v, measured %v", v.Node, v.Target, v.Requested, v.Measured)
+}
+
+// Err returns error if any of measured values differs beyond tolerance.
+func (v Verification) Err() error {
+	if len(v.Violations) == 0 {
+		return nil
+	}
+	return fmt.Errorf("conditions on %s are not effective: %s", v.Node, strings.Join(v.Violations, "; "))
+}
+
+// VerifyConditions probes from n to a node in target addrs of opts that has no conditions towards n.
+// Rtt and loss are measured with ping, upload and download rates with a tcp transfer if they are limited
+// and no loss is requested. Requested values are merged from all conditions that are active on n for the target,
+// e.g. region delays are added to the latency of opts.
+func (c *Cluster) VerifyConditions(ctx context.Context, n Node, opts network.Options, tolerance network.Tolerance) (Verification, error) {
+	target, err := c.verifyTarget(n, opts)
+	if err != nil {
+		return Verification{}, err
+	}
+	effective, err := network.Effective(n.ActiveConditions(), target.IP())
+	if err != nil {
+		return Verification{}, err
+	}
+	v := Verification{Node: n.UID(), Target: target.UID(), Requested: effective.Expected()}
+	src, dst := c.output(n), c.output(target)
+	v.Measured.RTT, v.Measured.Loss, err = network.Ping(ctx, src, target.IP(), verifyPings)
+	if err != nil {
+		return v, fmt.Errorf("ping from %s to %s failed: %v", n.UID(), target.UID(), err)
+	}
+	// tcp rate is limited by loss, so rates are sampled only when no loss is requested
+	if v.Requested.Loss == 0 && v.Measured.Loss < 100 {
+		if effective.BW != 0 {
+			v.Measured.Upload, err = network.Throughput(ctx, src, dst, target.IP(), sampleSize(effective.BW))
+			if err != nil {
+				return v, fmt.Errorf("upload sample from %s failed: %v", n.UID(), err)
+			}
+		}
+		// peers behind nat can't accept connections
+		if effective.DownloadBW != 0 && !behindNAT(n) {
+			v.Measured.Download, err = network.Throughput(ctx, dst, src, n.IP(), sampleSize(effective.DownloadBW))
+			if err != nil {
+				return v, fmt.Errorf("download sample to %s failed: %v", n.UID(), err)
+			}
+		}
+	}
+	v.Violations = tolerance.Compare(v.Requested, v.Measured)
+	log.Info("verified network conditions", "result", v, "violations", len(v.Violations))
+	return v, nil
+}
+
+func (c *Cluster) output(n Node) network.OutputExecutor {
+	return func(ctx context.Context, cmd []string) (string, error) {
+		return c.Backend.Output(ctx, n.UID(), cmd)
+	}
+}
+
+// verifyTarget returns first reachable node in target addrs without conditions towards n.
+func (c *Cluster) verifyTarget(n Node, opts network.Options) (Node, error) {
+	for _, target := range c.GetNodes() {
+		if target.UID() == n.UID() || behindNAT(target) || !inTargets(target.IP(), opts.TargetAddrs) {
+			continue
+		}
+		back, err := network.Effective(target.ActiveConditions(), n.IP())
+		if err != nil {
+			return nil, err
+		}
+		if reflect.DeepEqual(back, network.Options{}) {
+			return target, nil
+		}
+	}
+	return nil, ErrNoVerifyTarget
+}
+
+func inTargets(ip string, targets []string) bool {
+	addr := net.ParseIP(ip)
+	for _, target := range targets {
+		if _, ipnet, err := net.ParseCIDR(target); err == nil {
+			if ipnet.Contains(addr) {
+				return true
+			}
+		} else if addr.Equal(net.ParseIP(target)) {
+			return true
+		}
+	}
+	return false
+}
+
+// sampleSize returns size in kilobytes that is transferred in verifyTransfer with rate in kb.
+func sampleSize(rate int) int {
+	size := int(float64(rate) * verifyTransfer.Seconds() / 8)
+	if size < 16 {
+		return 16
+	}
+	return size
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	docker "docker.io/go-docker"
+	"github.com/ethereum/go-ethereum/log"
+
+	"github.com/status-im/status-scale/cluster"
+	"github.com/status-im/status-scale/dockershim"
+	"github.com/status-im/status-scale/network"
+	"github.com/status-im/status-scale/scenario"
+)
+
+var (
+	prefix     = flag.String("prefix", "scale", "prefix for containers")
+	cidr       = flag.String("cidr", "10.0.200.0/24", "network cidr")
+	verbosity  = flag.String("log", "info", "log level")
+	keep       = flag.Bool("keep", false, "keep cluster after scenario is finished")
+	attach     = flag.Bool("attach", false, "attach to a cluster with the same prefix that was kept by previous run, steps of the scenario are not deployed")
+	rundir     = flag.String("rundir", filepath.Join(os.TempDir(), "status-scale"), "directory for cluster state")
+	emulator   = flag.String("emulator", "netem", "network emulator: netem or comcast")
+	output     = flag.String("out", "", "file for results. stdout is used if empty")
+	statusd    = flag.String("statusd", "statusteam/statusd-debug:latest", "image for status go with comcast")
+	bootnode   = flag.String("bootnode", "statusteam/bootnode-debug:latest", "image for bootnode with comcast")
+	rendezvous = flag.String("rendezvous", "statusteam/rendezvous-debug:latest", "image for rendezvous with comcast")
+	client     = flag.String("client", "statusteam/client-debug:latest", "image for status client with comcast")
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [options] <scenario.yaml|scenario.json>\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+	if flag.NArg() != 1 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	if err := run(flag.Arg(0)); err != nil {
+		log.Error("scenario failed", "error", err)
+		os.Exit(1)
+	}
+}
+
+func run(path string) error {
+	handler := log.StreamHandler(os.Stderr, log.TerminalFormat(true))
+	level, err := log.LvlFromString(strings.ToLower(*verbosity))
+	if err != nil {
+		return err
+	}
+	log.Root().SetHandler(log.LvlFilterHandler(level, handler))
+
+	s, err := scenario.Load(path)
+	if err != nil {
+		return err
+	}
+	var w io.Writer = os.Stdout
+	if len(*output) != 0 {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	dclient, err := docker.NewEnvClient()
+	if err != nil {
+		return err
+	}
+	ipam, err := cluster.NewIPAM(*cidr)
+	if err != nil {
+		return err
+	}
+	c := cluster.NewCluster(
+		*prefix, ipam, dockershim.NewShim(dclient),
+		*statusd, *client, *bootnode, *rendezvous, *keep,
+	)
+	c.RunDir = *rundir
+	c.Emulator, err = network.NewEmulator(*emulator)
+	if err != nil {
+		return err
+	}
+	defer c.Clean(context.Background())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-sig
+		log.Info("received interrupt. stopping scenario")
+		cancel()
+	}()
+	if *attach {
+		if err := c.Attach(ctx); err != nil {
+			return err
+		}
+	}
+	return scenario.NewRunner(c, w).Run(ctx, s)
+}
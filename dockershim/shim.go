@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"io/ioutil"
+	"net"
 	"strings"
 	"time"
 
@@ -36,6 +37,9 @@ type CreateOpts struct {
 	Image               string
 	IPs                 map[string]IpOpts
 	Ports               []string
+	Sysctls             map[string]string
+	// Volumes maps names of volumes to paths in the container. Volumes are kept when container is removed.
+	Volumes map[string]string
 }
 
 type NetOpts struct {
@@ -49,53 +53,73 @@ type DockerShim struct {
 	client *docker.Client
 }
 
+const (
+	executeTimeout = 10 * time.Second
+	outputTimeout  = 2 * time.Minute
+)
+
 func (p DockerShim) Execute(ctx context.Context, id string, cmd []string) error {
+	_, err := p.exec(ctx, id, cmd, executeTimeout)
+	return err
+}
+
+// Output executes command and returns its combined stdout and stderr. Output is returned
+// even if command failed. Command must finish in 2 minutes.
+func (p DockerShim) Output(ctx context.Context, id string, cmd []string) (string, error) {
+	return p.exec(ctx, id, cmd, outputTimeout)
+}
+
+func (p DockerShim) exec(ctx context.Context, id string, cmd []string, timeout time.Duration) (string, error) {
+	// with tty output is not multiplexed and can be read as is
 	resp, err := p.client.ContainerExecCreate(ctx, id, types.ExecConfig{
 		Cmd:          cmd,
 		Privileged:   true,
+		Tty:          true,
 		AttachStdout: true,
 		AttachStderr: true,
 	})
 	if err != nil {
-		return err
+		return "", err
 	}
-	hj, err := p.client.ContainerExecAttach(context.TODO(), resp.ID, types.ExecConfig{})
+	hj, err := p.client.ContainerExecAttach(context.TODO(), resp.ID, types.ExecConfig{Tty: true})
 	if err != nil {
-		return err
+		return "", err
 	}
 	defer hj.Close()
+	output := make(chan string, 1)
+	go func() {
+		data, err := ioutil.ReadAll(hj.Reader)
+		if err != nil {
+			data = append(data, err.Error()...)
+		}
+		output <- string(data)
+	}()
 	start := time.Now()
-	for time.Since(start) < 10*time.Second {
+	for time.Since(start) < timeout {
 		inspect, err := p.client.ContainerExecInspect(ctx, resp.ID)
 		if err != nil {
-			return err
+			return "", err
 		}
 		if !inspect.Running {
+			var out string
+			select {
+			case out = <-output:
+			case <-time.After(time.Second):
+			}
 			if inspect.ExitCode != 0 {
-				data, err := ioutil.ReadAll(hj.Conn)
-				errmsg := string(data)
-				if err != nil {
-					errmsg = err.Error()
-				}
-				return fmt.Errorf("command `%+v` failed:\n%v", strings.Join(cmd, " "), errmsg)
+				return out, fmt.Errorf("command `%+v` failed with code %d:\n%v", strings.Join(cmd, " "), inspect.ExitCode, out)
 			}
-			return nil
+			return out, nil
 		}
+		time.Sleep(10 * time.Millisecond)
 	}
-	return fmt.Errorf("command `%+v` timed out", strings.Join(cmd, " "))
+	return "", fmt.Errorf("command `%+v` timed out", strings.Join(cmd, " "))
 }
 
 func (p DockerShim) Create(ctx context.Context, id string, opts CreateOpts) error {
 	endpoints := map[string]*network.EndpointSettings{}
 	for iface, opts := range opts.IPs {
-		endpoints[iface] = &network.EndpointSettings{
-			IPAMConfig: &network.EndpointIPAMConfig{
-				IPv4Address: opts.IP,
-			},
-			IPAddress: opts.IP,
-			NetworkID: opts.NetID,
-		}
-
+		endpoints[iface] = endpointSettings(opts)
 	}
 	ports, portsMap, err := nat.ParsePortSpecs(opts.Ports)
 	if err != nil {
@@ -109,6 +133,13 @@ func (p DockerShim) Create(ctx context.Context, id string, opts CreateOpts) erro
 			Target: opts.ContainerConfigPath,
 		})
 	}
+	for name, target := range opts.Volumes {
+		mounts = append(mounts, mount.Mount{
+			Type:   mount.TypeVolume,
+			Source: name,
+			Target: target,
+		})
+	}
 	_, err = p.client.ContainerCreate(ctx, &container.Config{
 		Entrypoint:   []string{opts.Entrypoint},
 		Cmd:          opts.Cmd,
@@ -118,6 +149,7 @@ func (p DockerShim) Create(ctx context.Context, id string, opts CreateOpts) erro
 		PortBindings: portsMap,
 		Mounts:       mounts,
 		CapAdd:       strslice.StrSlice{"NET_ADMIN"},
+		Sysctls:      opts.Sysctls,
 	}, &network.NetworkingConfig{
 		EndpointsConfig: endpoints,
 	}, id)
@@ -127,18 +159,67 @@ func (p DockerShim) Create(ctx context.Context, id string, opts CreateOpts) erro
 	return p.client.ContainerStart(ctx, id, types.ContainerStartOptions{})
 }
 
+func endpointSettings(opts IpOpts) *network.EndpointSettings {
+	settings := &network.EndpointSettings{
+		IPAMConfig: &network.EndpointIPAMConfig{},
+		NetworkID:  opts.NetID,
+	}
+	if ip := net.ParseIP(opts.IP); ip != nil && ip.To4() == nil {
+		settings.IPAMConfig.IPv6Address = opts.IP
+		settings.GlobalIPv6Address = opts.IP
+	} else {
+		settings.IPAMConfig.IPv4Address = opts.IP
+		settings.IPAddress = opts.IP
+	}
+	return settings
+}
+
+// ConnectNetwork connects running container to one more network.
+func (p DockerShim) ConnectNetwork(ctx context.Context, id string, opts IpOpts) error {
+	return p.client.NetworkConnect(ctx, opts.NetID, id, endpointSettings(opts))
+}
+
 func (p DockerShim) Reboot(ctx context.Context, id string) error {
 	timeout := 5 * time.Second
 	return p.client.ContainerRestart(ctx, id, &timeout)
 }
 
+// Pause freezes all processes in the container.
+func (p DockerShim) Pause(ctx context.Context, id string) error {
+	return p.client.ContainerPause(ctx, id)
+}
+
+func (p DockerShim) Unpause(ctx context.Context, id string) error {
+	return p.client.ContainerUnpause(ctx, id)
+}
+
+// Stop sends SIGTERM to the container and kills it if it is not stopped in 10s.
+func (p DockerShim) Stop(ctx context.Context, id string) error {
+	timeout := 10 * time.Second
+	return p.client.ContainerStop(ctx, id, &timeout)
+}
+
+// Kill sends SIGKILL to the container. Container can be started again with the same data.
+func (p DockerShim) Kill(ctx context.Context, id string) error {
+	return p.client.ContainerKill(ctx, id, "SIGKILL")
+}
+
+func (p DockerShim) Start(ctx context.Context, id string) error {
+	return p.client.ContainerStart(ctx, id, types.ContainerStartOptions{})
+}
+
 func (p DockerShim) EnsureNetwork(ctx context.Context, opts NetOpts) (string, error) {
 	// check that cidr intersects
-	net, err := p.client.NetworkInspect(ctx, opts.NetID, types.NetworkInspectOptions{})
+	info, err := p.client.NetworkInspect(ctx, opts.NetID, types.NetworkInspectOptions{})
 	if err == nil {
-		return net.ID, err
+		return info.ID, err
+	}
+	ip, _, err := net.ParseCIDR(opts.CIDR)
+	if err != nil {
+		return "", err
 	}
 	rst, err := p.client.NetworkCreate(ctx, opts.NetName, types.NetworkCreate{
+		EnableIPv6: ip.To4() == nil,
 		IPAM: &network.IPAM{
 			Config: []network.IPAMConfig{{Subnet: opts.CIDR}},
 		},
@@ -154,6 +235,10 @@ func (p DockerShim) Remove(ctx context.Context, id string) error {
 	return p.client.ContainerRemove(ctx, id, types.ContainerRemoveOptions{Force: true})
 }
 
+func (p DockerShim) RemoveVolume(ctx context.Context, name string) error {
+	return p.client.VolumeRemove(ctx, name, true)
+}
+
 func (p DockerShim) ConnectionInfo(ctx context.Context, name string, target int) ([]nat.PortBinding, error) {
 	info, err := p.client.ContainerInspect(ctx, name)
 	if err != nil {
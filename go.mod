@@ -18,6 +18,7 @@ require (
 	github.com/status-im/status-console-client v0.0.0-20190701050511-1a3e62a7564f
 	github.com/status-im/status-go v0.26.0-beta.0
 	github.com/stretchr/testify v1.3.0
+	gopkg.in/yaml.v2 v2.2.2
 )
 
 replace github.com/ethereum/go-ethereum v1.8.27 => github.com/status-im/go-ethereum v1.8.27-status
@@ -0,0 +1,79 @@
+package network
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// LatencyMatrix is a symmetric table of round trip times in milliseconds between regions.
+type LatencyMatrix map[string]map[string]int
+
+// Set stores rtt between regions a and b.
+func (m LatencyMatrix) Set(a, b string, rtt int) {
+	if m[a] == nil {
+		m[a] = map[string]int{}
+	}
+	m[a][b] = rtt
+}
+
+// RTT returns rtt between regions a and b. Value can be defined in any direction.
+func (m LatencyMatrix) RTT(a, b string) (int, bool) {
+	if rtt, exist := m[a][b]; exist {
+		return rtt, true
+	}
+	rtt, exist := m[b][a]
+	return rtt, exist
+}
+
+// Validate checks that values are not negative and not defined differently for two directions.
+func (m LatencyMatrix) Validate() error {
+	for a := range m {
+		for b, rtt := range m[a] {
+			if rtt < 0 {
+				return fmt.Errorf("rtt between %s and %s is negative", a, b)
+			}
+			if other, exist := m[b][a]; exist && other != rtt {
+				return fmt.Errorf("rtt between %s and %s is not symmetric: %d and %d", a, b, rtt, other)
+			}
+		}
+	}
+	return nil
+}
+
+// ReadLatencyMatrix reads csv with region, region, rtt in every row, e.g. eu,us,90.
+func ReadLatencyMatrix(r io.Reader) (LatencyMatrix, error) {
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = 3
+	reader.TrimLeadingSpace = true
+	m := LatencyMatrix{}
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		rtt, err := strconv.Atoi(strings.TrimSpace(record[2]))
+		if err != nil {
+			return nil, fmt.Errorf("invalid rtt %s: %v", record[2], err)
+		}
+		m.Set(strings.TrimSpace(record[0]), strings.TrimSpace(record[1]), rtt)
+	}
+	return m, m.Validate()
+}
+
+// LoadLatencyMatrix reads latency matrix from a csv file.
+func LoadLatencyMatrix(path string) (LatencyMatrix, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadLatencyMatrix(f)
+}
@@ -0,0 +1,26 @@
+package network
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadLatencyMatrix(t *testing.T) {
+	m, err := ReadLatencyMatrix(strings.NewReader(`
+# region, region, rtt
+eu, us, 90
+eu, asia, 200
+eu, eu, 10
+`))
+	require.NoError(t, err)
+	rtt, exist := m.RTT("us", "eu")
+	require.True(t, exist)
+	require.Equal(t, 90, rtt)
+	_, exist = m.RTT("us", "asia")
+	require.False(t, exist)
+
+	_, err = ReadLatencyMatrix(strings.NewReader("eu,us,90\nus,eu,100\n"))
+	require.Error(t, err)
+}
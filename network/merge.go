@@ -0,0 +1,181 @@
+package network
+
+import (
+	"fmt"
+	"math"
+	"net"
+	"reflect"
+	"sort"
+)
+
+// Merge combines options that are applied to the same traffic. Latencies and jitters are added,
+// loss, duplication and corruption are combined as independent probabilities, reordering is the maximum
+// and bandwidth is the minimum. Target addresses are not merged.
+func Merge(options ...Options) Options {
+	var rst Options
+	for _, opt := range options {
+		if len(rst.TargetInterface) == 0 {
+			rst.TargetInterface = opt.TargetInterface
+		}
+		rst.Latency += opt.Latency
+		rst.Jitter += opt.Jitter
+		if len(rst.Distribution) == 0 {
+			rst.Distribution = opt.Distribution
+		}
+		rst.PacketLoss = combine(rst.PacketLoss, opt.PacketLoss)
+		rst.Duplicate = combine(rst.Duplicate, opt.Duplicate)
+		rst.Corrupt = combine(rst.Corrupt, opt.Corrupt)
+		if opt.Reorder > rst.Reorder {
+			rst.Reorder = opt.Reorder
+		}
+		rst.BW = minBW(rst.BW, opt.BW)
+
+		rst.DownloadLatency += opt.DownloadLatency
+		rst.DownloadPacketLoss = combine(rst.DownloadPacketLoss, opt.DownloadPacketLoss)
+		rst.DownloadBW = minBW(rst.DownloadBW, opt.DownloadBW)
+	}
+	return rst
+}
+
+// combine returns probability in percents that at least one of independent events happens.
+func combine(a, b int) int {
+	return int(math.Round(100 - float64((100-a)*(100-b))/100))
+}
+
+// minBW returns the lower bandwidth, zero is unlimited.
+func minBW(a, b int) int {
+	if a == 0 || (b != 0 && b < a) {
+		return b
+	}
+	return a
+}
+
+// Effective returns merged options that are applied to traffic sent to addr.
+func Effective(options []Options, addr string) (Options, error) {
+	dst, err := prefix(addr)
+	if err != nil {
+		return Options{}, err
+	}
+	var matched []Options
+	for _, opt := range options {
+		targets, err := targets(opt)
+		if err != nil {
+			return Options{}, err
+		}
+		for _, t := range targets {
+			if covers(t, dst) {
+				matched = append(matched, opt)
+				break
+			}
+		}
+	}
+	return Merge(matched...), nil
+}
+
+// prefix parses an address or cidr. Addresses are returned as a single host network.
+func prefix(addr string) (*net.IPNet, error) {
+	if ip := net.ParseIP(addr); ip != nil {
+		if v4 := ip.To4(); v4 != nil {
+			return &net.IPNet{IP: v4, Mask: net.CIDRMask(32, 32)}, nil
+		}
+		return &net.IPNet{IP: ip, Mask: net.CIDRMask(128, 128)}, nil
+	}
+	_, ipnet, err := net.ParseCIDR(addr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid target address %s", addr)
+	}
+	return ipnet, nil
+}
+
+// targets returns target prefixes of options. Options without targets are applied to all addresses.
+func targets(opt Options) ([]*net.IPNet, error) {
+	if len(opt.TargetAddrs) == 0 {
+		return []*net.IPNet{
+			{IP: net.IPv4zero.To4(), Mask: net.CIDRMask(0, 32)},
+			{IP: net.IPv6zero, Mask: net.CIDRMask(0, 128)},
+		}, nil
+	}
+	rst := make([]*net.IPNet, 0, len(opt.TargetAddrs))
+	for _, addr := range opt.TargetAddrs {
+		p, err := prefix(addr)
+		if err != nil {
+			return nil, err
+		}
+		rst = append(rst, p)
+	}
+	return rst, nil
+}
+
+// covers is true if every address of inner is in outer.
+func covers(outer, inner *net.IPNet) bool {
+	outerOnes, outerBits := outer.Mask.Size()
+	innerOnes, innerBits := inner.Mask.Size()
+	return outerBits == innerBits && outerOnes <= innerOnes && outer.Contains(inner.IP)
+}
+
+// band is a set of merged options and destinations that are directed to it.
+type band struct {
+	opts     Options
+	prefixes []*net.IPNet
+}
+
+// bands splits destinations by target prefixes of options. Every packet is matched by the most specific prefix
+// that contains its address, and gets options of every target that contains this prefix. Prefixes with equal
+// merged options share a band.
+func bands(options []Options) ([]band, error) {
+	var (
+		all  []*net.IPNet
+		seen = map[string]bool{}
+		nets = make([][]*net.IPNet, len(options))
+	)
+	for i, opt := range options {
+		targets, err := targets(opt)
+		if err != nil {
+			return nil, err
+		}
+		nets[i] = targets
+		for _, t := range targets {
+			if !seen[t.String()] {
+				seen[t.String()] = true
+				all = append(all, t)
+			}
+		}
+	}
+	sort.Slice(all, func(i, j int) bool {
+		hi, hj := hostBits(all[i]), hostBits(all[j])
+		if hi != hj {
+			return hi < hj
+		}
+		return all[i].String() < all[j].String()
+	})
+	var rst []band
+	for _, p := range all {
+		var matched []Options
+		for i := range options {
+			for _, t := range nets[i] {
+				if covers(t, p) {
+					matched = append(matched, options[i])
+					break
+				}
+			}
+		}
+		merged := Merge(matched...)
+		found := false
+		for i := range rst {
+			if reflect.DeepEqual(rst[i].opts, merged) {
+				rst[i].prefixes = append(rst[i].prefixes, p)
+				found = true
+				break
+			}
+		}
+		if !found {
+			rst = append(rst, band{opts: merged, prefixes: []*net.IPNet{p}})
+		}
+	}
+	return rst, nil
+}
+
+func hostBits(p *net.IPNet) int {
+	ones, bits := p.Mask.Size()
+	return bits - ones
+}
@@ -0,0 +1,200 @@
+package network
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+)
+
+const (
+	// prio qdisc supports at most 16 bands, first band is used for traffic without conditions.
+	maxNetemRules = 15
+
+	// ifbDevice receives ingress traffic of the interface
+	ifbDevice = "ifb0"
+
+	tbfBurst   = "32kbit"
+	tbfLatency = "400ms"
+)
+
+// Netem applies conditions with tc. Options that target the same destination are merged, and every distinct
+// set of merged options gets its own prio band with netem qdisc, and tbf qdisc if bandwidth is limited.
+// Traffic is directed to the band with u32 filters by destination address, more specific prefixes are matched first.
+// Download conditions are applied in the same way to ingress traffic that is redirected to ifb device,
+// and matched by source address.
+type Netem struct {
+	// Interface is eth0 if empty.
+	Interface string
+}
+
+func (n Netem) dev() string {
+	if len(n.Interface) == 0 {
+		return "eth0"
+	}
+	return n.Interface
+}
+
+func (n Netem) Apply(ctx context.Context, shell Executor, options ...Options) error {
+	var upload, download []Options
+	for _, opt := range options {
+		if err := n.validate(opt); err != nil {
+			return err
+		}
+		if up := opt.upload(); !up.Empty() {
+			upload = append(upload, up)
+		}
+		if down := opt.download(); !down.Empty() {
+			download = append(download, down)
+		}
+	}
+	up, err := bands(upload)
+	if err != nil {
+		return err
+	}
+	down, err := bands(download)
+	if err != nil {
+		return err
+	}
+	if len(up) > maxNetemRules || len(down) > maxNetemRules {
+		return fmt.Errorf("%v: netem supports at most %d distinct sets of conditions in each direction, got %d and %d",
+			ErrUnsupported, maxNetemRules, len(up), len(down))
+	}
+	// qdiscs don't exist if conditions were never applied, so errors are ignored
+	_ = shell(ctx, tc(n.dev(), "qdisc", "del", "root"))
+	_ = shell(ctx, tc(n.dev(), "qdisc", "del", "ingress"))
+	_ = shell(ctx, tc(ifbDevice, "qdisc", "del", "root"))
+	if len(up) != 0 {
+		if err := tree(ctx, shell, n.dev(), "dst", up); err != nil {
+			return err
+		}
+	}
+	if len(down) == 0 {
+		return nil
+	}
+	// ingress traffic can't be shaped, so it is redirected to ifb device and shaped on its egress
+	_ = shell(ctx, []string{"ip", "link", "add", ifbDevice, "type", "ifb"})
+	for _, cmd := range [][]string{
+		{"ip", "link", "set", "dev", ifbDevice, "up"},
+		append(tc(n.dev(), "qdisc", "add", "ingress"), "handle", "ffff:"),
+		append(tc(n.dev(), "filter", "add", "ffff:"), "protocol", "all", "prio", "1", "u32", "match", "u32", "0", "0",
+			"action", "mirred", "egress", "redirect", "dev", ifbDevice),
+	} {
+		if err := shell(ctx, cmd); err != nil {
+			return err
+		}
+	}
+	return tree(ctx, shell, ifbDevice, "src", down)
+}
+
+// tree creates prio qdisc on the device with a band for each set of options. Traffic is matched by destination
+// or source address.
+func tree(ctx context.Context, shell Executor, dev, match string, bands []band) error {
+	root := append(tc(dev, "qdisc", "add", "root"), "handle", "1:", "prio", "bands", strconv.Itoa(len(bands)+1), "priomap")
+	for i := 0; i < 16; i++ {
+		root = append(root, "0")
+	}
+	if err := shell(ctx, root); err != nil {
+		return err
+	}
+	for i, b := range bands {
+		for _, cmd := range rule(dev, match, i+2, b) {
+			if err := shell(ctx, cmd); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (n Netem) validate(opt Options) error {
+	if len(opt.TargetInterface) != 0 && opt.TargetInterface != n.dev() {
+		return fmt.Errorf("%v: netem is configured for %s, got %s", ErrUnsupported, n.dev(), opt.TargetInterface)
+	}
+	if (opt.Jitter != 0 || opt.Reorder != 0) && opt.Latency == 0 {
+		return fmt.Errorf("%v: jitter and reordering require latency", ErrUnsupported)
+	}
+	switch opt.Distribution {
+	case "", "uniform", "normal", "pareto", "paretonormal":
+	default:
+		return fmt.Errorf("%v: unknown distribution %s", ErrUnsupported, opt.Distribution)
+	}
+	return nil
+}
+
+func tc(dev, object, action, parent string) []string {
+	cmd := []string{"tc", object, action, "dev", dev}
+	switch parent {
+	case "root", "ingress":
+		return append(cmd, parent)
+	}
+	return append(cmd, "parent", parent)
+}
+
+// rule returns commands that create qdiscs for the band and direct traffic to it.
+func rule(dev, match string, index int, b band) [][]string {
+	opt := b.opts
+	// class ids and handles are parsed by tc as hex numbers
+	class := fmt.Sprintf("1:%x", index)
+	handle := fmt.Sprintf("%x:", index<<4)
+	netem := append(tc(dev, "qdisc", "add", class), "handle", handle, "netem")
+	if opt.Latency != 0 {
+		netem = append(netem, "delay", ms(opt.Latency))
+		if opt.Jitter != 0 {
+			netem = append(netem, ms(opt.Jitter))
+			if len(opt.Distribution) != 0 && opt.Distribution != "uniform" {
+				netem = append(netem, "distribution", opt.Distribution)
+			}
+		}
+	}
+	if opt.PacketLoss != 0 {
+		netem = append(netem, "loss", percent(opt.PacketLoss))
+	}
+	if opt.Duplicate != 0 {
+		netem = append(netem, "duplicate", percent(opt.Duplicate))
+	}
+	if opt.Corrupt != 0 {
+		netem = append(netem, "corrupt", percent(opt.Corrupt))
+	}
+	if opt.Reorder != 0 {
+		netem = append(netem, "reorder", percent(opt.Reorder))
+	}
+	cmds := [][]string{netem}
+	if opt.BW != 0 {
+		cmds = append(cmds, append(tc(dev, "qdisc", "add", handle+"1"), "handle", fmt.Sprintf("%x:", index<<4+1),
+			"tbf", "rate", strconv.Itoa(opt.BW)+"kbit", "burst", tbfBurst, "latency", tbfLatency))
+	}
+	for _, p := range b.prefixes {
+		cmds = append(cmds, filter(dev, match, class, p))
+	}
+	return cmds
+}
+
+// filter directs traffic to the class. Filters of more specific prefixes have lower priority and are matched first,
+// filters with the same priority must have the same protocol.
+func filter(dev, match, class string, p *net.IPNet) []string {
+	ones, bits := p.Mask.Size()
+	proto, prio, selector := "ip", 1+32-ones, []string{"ip", match}
+	if bits == 128 {
+		proto, prio, selector = "ipv6", 34+128-ones, []string{"ip6", match}
+	}
+	addr := p.String()
+	if ones == bits {
+		addr = p.IP.String()
+	}
+	cmd := append(tc(dev, "filter", "add", "1:"), "protocol", proto, "prio", strconv.Itoa(prio), "u32", "match")
+	if ones == 0 {
+		cmd = append(cmd, "u32", "0", "0")
+	} else {
+		cmd = append(append(cmd, selector...), addr)
+	}
+	return append(cmd, "flowid", class)
+}
+
+func ms(v int) string {
+	return strconv.Itoa(v) + "ms"
+}
+
+func percent(v int) string {
+	return strconv.Itoa(v) + "%"
+}
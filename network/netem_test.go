@@ -0,0 +1,103 @@
+package network
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func record(cmds *[]string) Executor {
+	return func(_ context.Context, cmd []string) error {
+		*cmds = append(*cmds, strings.Join(cmd, " "))
+		return nil
+	}
+}
+
+func TestNetemApply(t *testing.T) {
+	var cmds []string
+	require.NoError(t, Netem{}.Apply(context.TODO(), record(&cmds),
+		Options{Latency: 100, Jitter: 20, Distribution: "normal", Reorder: 25, BW: 1000, TargetAddrs: []string{"10.0.0.2", "fd00::2"}},
+		Options{PacketLoss: 10},
+	))
+	require.Equal(t, []string{
+		"tc qdisc del dev eth0 root",
+		"tc qdisc del dev eth0 ingress",
+		"tc qdisc del dev ifb0 root",
+		"tc qdisc add dev eth0 root handle 1: prio bands 3 priomap 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0",
+		"tc qdisc add dev eth0 parent 1:2 handle 20: netem delay 100ms 20ms distribution normal loss 10% reorder 25%",
+		"tc qdisc add dev eth0 parent 20:1 handle 21: tbf rate 1000kbit burst 32kbit latency 400ms",
+		"tc filter add dev eth0 parent 1: protocol ip prio 1 u32 match ip dst 10.0.0.2 flowid 1:2",
+		"tc filter add dev eth0 parent 1: protocol ipv6 prio 34 u32 match ip6 dst fd00::2 flowid 1:2",
+		"tc qdisc add dev eth0 parent 1:3 handle 30: netem loss 10%",
+		"tc filter add dev eth0 parent 1: protocol ip prio 33 u32 match u32 0 0 flowid 1:3",
+		"tc filter add dev eth0 parent 1: protocol ipv6 prio 162 u32 match u32 0 0 flowid 1:3",
+	}, cmds)
+
+	cmds = nil
+	require.NoError(t, Netem{}.Apply(context.TODO(), record(&cmds)))
+	require.Equal(t, []string{
+		"tc qdisc del dev eth0 root",
+		"tc qdisc del dev eth0 ingress",
+		"tc qdisc del dev ifb0 root",
+	}, cmds)
+}
+
+func TestNetemDownload(t *testing.T) {
+	var cmds []string
+	require.NoError(t, Netem{}.Apply(context.TODO(), record(&cmds),
+		Options{DownloadBW: 240, DownloadLatency: 100, TargetAddrs: []string{"10.0.0.0/24"}},
+	))
+	require.Equal(t, []string{
+		"tc qdisc del dev eth0 root",
+		"tc qdisc del dev eth0 ingress",
+		"tc qdisc del dev ifb0 root",
+		"ip link add ifb0 type ifb",
+		"ip link set dev ifb0 up",
+		"tc qdisc add dev eth0 ingress handle ffff:",
+		"tc filter add dev eth0 parent ffff: protocol all prio 1 u32 match u32 0 0 action mirred egress redirect dev ifb0",
+		"tc qdisc add dev ifb0 root handle 1: prio bands 2 priomap 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0 0",
+		"tc qdisc add dev ifb0 parent 1:2 handle 20: netem delay 100ms",
+		"tc qdisc add dev ifb0 parent 20:1 handle 21: tbf rate 240kbit burst 32kbit latency 400ms",
+		"tc filter add dev ifb0 parent 1: protocol ip prio 9 u32 match ip src 10.0.0.0/24 flowid 1:2",
+	}, cmds)
+}
+
+func TestNetemValidate(t *testing.T) {
+	var cmds []string
+	require.Error(t, Netem{}.Apply(context.TODO(), record(&cmds), Options{Jitter: 10}))
+	require.Error(t, Netem{}.Apply(context.TODO(), record(&cmds), Options{Latency: 10, Jitter: 10, Distribution: "unknown"}))
+	require.Error(t, Comcast{}.Apply(context.TODO(), record(&cmds), Options{Latency: 10, Reorder: 10}))
+	require.Error(t, Comcast{}.Apply(context.TODO(), record(&cmds), Options{Latency: 10}, Options{PacketLoss: 10}))
+	require.Empty(t, cmds)
+}
+
+func TestNetemStacking(t *testing.T) {
+	var (
+		latency = Options{Latency: 100, TargetAddrs: []string{"10.0.0.0/24"}}
+		loss    = Options{PacketLoss: 5, TargetAddrs: []string{"10.0.0.0/24"}}
+		region  = Options{Latency: 50, TargetAddrs: []string{"10.0.0.2"}}
+		bw      = Options{BW: 1000}
+	)
+	rst, err := bands([]Options{latency, loss, region, bw})
+	require.NoError(t, err)
+	require.Len(t, rst, 3)
+	require.Equal(t, Options{Latency: 150, PacketLoss: 5, BW: 1000}, rst[0].opts)
+	require.Equal(t, Options{Latency: 100, PacketLoss: 5, BW: 1000}, rst[1].opts)
+	require.Equal(t, Options{BW: 1000}, rst[2].opts)
+	require.Len(t, rst[2].prefixes, 2)
+
+	effective, err := Effective([]Options{latency, loss, region, bw}, "10.0.0.3")
+	require.NoError(t, err)
+	require.Equal(t, Options{Latency: 100, PacketLoss: 5, BW: 1000}, effective)
+
+	_, err = bands([]Options{{Latency: 10, TargetAddrs: []string{"10.0.0"}}})
+	require.Error(t, err)
+}
+
+func TestMerge(t *testing.T) {
+	require.Equal(t, Options{Latency: 30, PacketLoss: 28, BW: 500, DownloadBW: 200},
+		Merge(Options{Latency: 10, PacketLoss: 10, BW: 500}, Options{Latency: 20, PacketLoss: 20, BW: 1000, DownloadBW: 200}))
+	require.Equal(t, 100, Merge(Options{PacketLoss: 100}, Options{PacketLoss: 5}).PacketLoss)
+}
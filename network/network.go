@@ -3,21 +3,65 @@ package network
 import (
 	"context"
 	"errors"
+	"fmt"
 	"strconv"
 	"strings"
 )
 
 var (
 	ErrNothingToRun = errors.New("nothing to run")
+	ErrUnsupported  = errors.New("options are not supported by the emulator")
 )
 
-type executor func(context.Context, []string) error
+// Executor runs a command inside of the container.
+type Executor func(context.Context, []string) error
 
-func ComcastStart(shell executor, ctx context.Context, opt Options) error {
-	return ComcastStartSingle(shell, ctx, opt)
+// Emulator applies network conditions to the container using provided executor.
+// Apply replaces all conditions that were applied before, without options conditions are removed.
+type Emulator interface {
+	Apply(ctx context.Context, shell Executor, options ...Options) error
 }
 
-func ComcastStartSingle(shell executor, ctx context.Context, opt Options) error {
+// NewEmulator returns emulator by name, either netem or comcast.
+func NewEmulator(name string) (Emulator, error) {
+	switch name {
+	case "netem":
+		return Netem{}, nil
+	case "comcast":
+		return Comcast{}, nil
+	}
+	return nil, fmt.Errorf("unknown emulator %s", name)
+}
+
+// Comcast applies conditions with comcast binary. Comcast supports only latency, packet loss and bandwidth,
+// and a single set of options, every run replaces rules of the previous one.
+type Comcast struct{}
+
+func (Comcast) Apply(ctx context.Context, shell Executor, options ...Options) error {
+	if len(options) > 1 {
+		return fmt.Errorf("%v: comcast supports a single set of options, got %d", ErrUnsupported, len(options))
+	}
+	for _, opt := range options {
+		if opt.Jitter != 0 || opt.Reorder != 0 || opt.Duplicate != 0 || opt.Corrupt != 0 || !opt.download().Empty() {
+			return fmt.Errorf("%v: comcast supports only latency, packet loss and bandwidth for sent traffic", ErrUnsupported)
+		}
+	}
+	if err := ComcastStop(shell, ctx); err != nil {
+		return err
+	}
+	return ComcastStart(shell, ctx, options...)
+}
+
+func ComcastStart(shell Executor, ctx context.Context, options ...Options) error {
+	for _, opt := range options {
+		if err := ComcastStartSingle(shell, ctx, opt); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func ComcastStartSingle(shell Executor, ctx context.Context, opt Options) error {
 	cmd := []string{"comcast"}
 	if opt.Latency != 0 {
 		cmd = append(cmd, "-latency", strconv.Itoa(opt.Latency))
@@ -37,14 +81,50 @@ func ComcastStartSingle(shell executor, ctx context.Context, opt Options) error
 	return shell(ctx, cmd)
 }
 
-func ComcastStop(shell executor, ctx context.Context, options ...Options) error {
+func ComcastStop(shell Executor, ctx context.Context, options ...Options) error {
 	return shell(ctx, []string{"comcast", "-stop"})
 }
 
+// Options are applied to traffic that is sent to TargetAddrs. Download options are applied to traffic
+// that is received from TargetAddrs.
 type Options struct {
 	TargetInterface string   // comcast selects eth0 by default
 	TargetAddrs     []string // all addresses will be blocked by default
 	Latency         int      // milliseconds
 	PacketLoss      int      // percents
 	BW              int      //target bandwidth in kb
+
+	Jitter       int    // milliseconds, requires latency
+	Distribution string // distribution of jitter: uniform (default), normal, pareto or paretonormal
+	Reorder      int    // percents of packets that are sent immediately, requires latency
+	Duplicate    int    // percents
+	Corrupt      int    // percents
+
+	DownloadLatency    int // milliseconds
+	DownloadPacketLoss int // percents
+	DownloadBW         int // kb
+}
+
+// upload returns options without download conditions.
+func (o Options) upload() Options {
+	o.DownloadLatency, o.DownloadPacketLoss, o.DownloadBW = 0, 0, 0
+	return o
+}
+
+// download returns download conditions as options for received traffic.
+func (o Options) download() Options {
+	return Options{
+		TargetInterface: o.TargetInterface,
+		TargetAddrs:     o.TargetAddrs,
+		Latency:         o.DownloadLatency,
+		PacketLoss:      o.DownloadPacketLoss,
+		BW:              o.DownloadBW,
+	}
+}
+
+// Empty is true if options don't change traffic.
+func (o Options) Empty() bool {
+	return o.Latency == 0 && o.PacketLoss == 0 && o.BW == 0 &&
+		o.Jitter == 0 && o.Reorder == 0 && o.Duplicate == 0 && o.Corrupt == 0 &&
+		o.DownloadLatency == 0 && o.DownloadPacketLoss == 0 && o.DownloadBW == 0
 }
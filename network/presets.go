@@ -0,0 +1,71 @@
+package network
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Preset is a named set of conditions of a typical network. Latency and jitter are one-way delays
+// added to traffic sent by the peer, BW limits upload and DownloadBW limits download.
+type Preset struct {
+	Description string
+	Options     Options
+}
+
+// Presets are conditions of common mobile and fixed networks. Values are medians of public measurements,
+// they are not meant to be precise, only to make results of different experiments comparable.
+var Presets = map[string]Preset{
+	"edge": {
+		Description: "2G/EDGE: very high latency and jitter, 1% loss, 200kb up, 240kb down",
+		Options:     Options{Latency: 400, Jitter: 100, Distribution: "normal", PacketLoss: 1, BW: 200, DownloadBW: 240},
+	},
+	"3g": {
+		Description: "3G/HSPA: high latency, 750kb up, 1.6mb down",
+		Options:     Options{Latency: 150, Jitter: 30, Distribution: "normal", BW: 750, DownloadBW: 1600},
+	},
+	"lte": {
+		Description: "4G/LTE: low latency, 12mb up, 40mb down",
+		Options:     Options{Latency: 40, Jitter: 10, Distribution: "normal", BW: 12000, DownloadBW: 40000},
+	},
+	"wifi": {
+		Description: "home WiFi: minimal latency, 30mb up, 100mb down",
+		Options:     Options{Latency: 5, Jitter: 2, BW: 30000, DownloadBW: 100000},
+	},
+	"wifi-congested": {
+		Description: "congested public WiFi: unstable latency, 2% loss, 2mb up, 4mb down",
+		Options:     Options{Latency: 20, Jitter: 15, Distribution: "pareto", PacketLoss: 2, BW: 2000, DownloadBW: 4000},
+	},
+	"satellite": {
+		Description: "geostationary satellite: 300ms one-way latency, 1% loss, 1mb up, 15mb down",
+		Options:     Options{Latency: 300, Jitter: 20, PacketLoss: 1, BW: 1000, DownloadBW: 15000},
+	},
+	"subway": {
+		Description: "mobile network in a subway: long tail of delays, 10% loss, 500kb up, 1mb down",
+		Options:     Options{Latency: 200, Jitter: 150, Distribution: "pareto", PacketLoss: 10, Reorder: 5, BW: 500, DownloadBW: 1000},
+	},
+	"offline": {
+		Description: "all traffic is dropped",
+		Options:     Options{PacketLoss: 100, DownloadPacketLoss: 100},
+	},
+}
+
+// PresetOptions returns options of the preset with given targets.
+func PresetOptions(name string, targets ...string) (Options, error) {
+	preset, exist := Presets[name]
+	if !exist {
+		return Options{}, fmt.Errorf("unknown network preset %s", name)
+	}
+	opts := preset.Options
+	opts.TargetAddrs = targets
+	return opts, nil
+}
+
+// PresetNames returns sorted names of all presets.
+func PresetNames() []string {
+	names := make([]string, 0, len(Presets))
+	for name := range Presets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
@@ -0,0 +1,175 @@
+package network
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ProbePort is used by throughput probe on the receiving container.
+const ProbePort = 5201
+
+var (
+	pingLossRe = regexp.MustCompile(`([\d.]+)% packet loss`)
+	// iputils: rtt min/avg/max/mdev = 0.04/0.05/0.07/0.01 ms, busybox: round-trip min/avg/max = 0.04/0.05/0.07 ms
+	pingRTTRe = regexp.MustCompile(`min/avg/max\S* = [\d.]+/([\d.]+)/`)
+)
+
+// OutputExecutor runs command in the container and returns its output.
+type OutputExecutor func(context.Context, []string) (string, error)
+
+// Ping sends count icmp echo requests to addr every second and returns average rtt and loss in percents.
+// Rtt is zero if all packets were lost.
+func Ping(ctx context.Context, shell OutputExecutor, addr string, count int) (time.Duration, float64, error) {
+	// ping exits with error if no replies were received, loss can be parsed from the output in that case
+	out, err := shell(ctx, []string{"ping", "-c", strconv.Itoa(count), addr})
+	rtt, loss, perr := ParsePing(out)
+	if perr != nil {
+		if err != nil {
+			return 0, 0, err
+		}
+		return 0, 0, perr
+	}
+	return rtt, loss, nil
+}
+
+// ParsePing parses summary of iputils or busybox ping.
+func ParsePing(out string) (rtt time.Duration, loss float64, err error) {
+	match := pingLossRe.FindStringSubmatch(out)
+	if match == nil {
+		return 0, 0, fmt.Errorf("packet loss not found in ping output: %s", out)
+	}
+	loss, err = strconv.ParseFloat(match[1], 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	match = pingRTTRe.FindStringSubmatch(out)
+	if match == nil {
+		if loss == 100 {
+			return 0, loss, nil
+		}
+		return 0, 0, fmt.Errorf("rtt not found in ping output: %s", out)
+	}
+	avg, err := strconv.ParseFloat(match[1], 64)
+	if err != nil {
+		return 0, 0, err
+	}
+	return time.Duration(avg * float64(time.Millisecond)), loss, nil
+}
+
+// Throughput sends size kilobytes over tcp from src to dst with address addr and returns rate in kb (kbit/s).
+// Time is measured around the whole command, so size should be big enough for the transfer to take
+// a few seconds. Listener is cancelled and awaited if the transfer fails.
+func Throughput(ctx context.Context, src, dst OutputExecutor, addr string, size int) (int, error) {
+	lctx, cancel := context.WithCancel(ctx)
+	listener := make(chan error, 1)
+	go func() {
+		_, err := dst(lctx, []string{"sh", "-c", fmt.Sprintf("nc -l -p %d > /dev/null", ProbePort)})
+		listener <- err
+	}()
+	stop := func() {
+		cancel()
+		<-listener
+	}
+	// there is no easy way to know when listener is ready
+	select {
+	case <-time.After(time.Second):
+	case err := <-listener:
+		cancel()
+		return 0, fmt.Errorf("listener on %s failed: %v", addr, err)
+	case <-ctx.Done():
+		stop()
+		return 0, ctx.Err()
+	}
+	start := time.Now()
+	_, err := src(ctx, []string{"sh", "-c", fmt.Sprintf(
+		"dd if=/dev/zero bs=1024 count=%d 2>/dev/null | nc %s %d", size, addr, ProbePort)})
+	elapsed := time.Since(start)
+	if err != nil {
+		stop()
+		return 0, err
+	}
+	err = <-listener
+	cancel()
+	if err != nil {
+		return 0, fmt.Errorf("listener on %s failed: %v", addr, err)
+	}
+	return int(float64(size*8) / elapsed.Seconds()), nil
+}
+
+// Measurement is a result of probes between two containers. Rates are in kb, zero if not measured.
+type Measurement struct {
+	RTT      time.Duration
+	Loss     float64
+	Upload   int
+	Download int
+}
+
+func (m Measurement) String() string {
+	return strings.Join([]string{
+		"rtt=" + m.RTT.String(),
+		"loss=" + strconv.FormatFloat(m.Loss, 'f', 1, 64) + "%",
+		"upload=" + formatKb(m.Upload),
+		"download=" + formatKb(m.Download),
+	}, " ")
+}
+
+func formatKb(kb int) string {
+	if kb == 0 {
+		return "-"
+	}
+	return strconv.Itoa(kb) + "kb"
+}
+
+// Expected returns measurement between a peer with options and a peer without conditions. Jitter is ignored.
+func (o Options) Expected() Measurement {
+	return Measurement{
+		RTT:      time.Duration(o.Latency+o.DownloadLatency) * time.Millisecond,
+		Loss:     100 * (1 - (1-float64(o.PacketLoss)/100)*(1-float64(o.DownloadPacketLoss)/100)),
+		Upload:   o.BW,
+		Download: o.DownloadBW,
+	}
+}
+
+// Tolerance is a maximum difference between requested and measured values.
+type Tolerance struct {
+	// RTT is an absolute difference, it should cover base rtt of the docker network.
+	RTT time.Duration
+	// Loss is a difference in percentage points.
+	Loss float64
+	// BW is a relative difference, e.g. 0.3 accepts rates from 70% to 130% of the requested rate.
+	BW float64
+}
+
+// DefaultTolerance is loose enough for 10 pings and a few seconds of transfer.
+var DefaultTolerance = Tolerance{RTT: 10 * time.Millisecond, Loss: 15, BW: 0.3}
+
+// Compare returns a description of every measured value that differs from requested beyond tolerance.
+// Rtt is not compared if all packets were lost, rates are compared only if both are not zero.
+func (t Tolerance) Compare(requested, measured Measurement) (violations []string) {
+	if measured.Loss < 100 {
+		if diff := measured.RTT - requested.RTT; diff > t.RTT || diff < -t.RTT {
+			violations = append(violations, fmt.Sprintf("rtt %v, requested %v", measured.RTT, requested.RTT))
+		}
+	}
+	if math.Abs(measured.Loss-requested.Loss) > t.Loss {
+		violations = append(violations, fmt.Sprintf("loss %.1f%%, requested %.1f%%", measured.Loss, requested.Loss))
+	}
+	rates := []struct {
+		name                string
+		requested, measured int
+	}{{"upload", requested.Upload, measured.Upload}, {"download", requested.Download, measured.Download}}
+	for _, r := range rates {
+		if r.requested == 0 || r.measured == 0 {
+			continue
+		}
+		if math.Abs(float64(r.measured-r.requested)) > t.BW*float64(r.requested) {
+			violations = append(violations, fmt.Sprintf("%s %dkb, requested %dkb", r.name, r.measured, r.requested))
+		}
+	}
+	return violations
+}
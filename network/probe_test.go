@@ -0,0 +1,85 @@
+package network
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParsePing(t *testing.T) {
+	for _, tc := range []struct {
+		desc   string
+		output string
+		rtt    time.Duration
+		loss   float64
+	}{
+		{
+			desc: "iputils",
+			output: `PING 10.1.0.3 (10.1.0.3) 56(84) bytes of data.
+
+--- 10.1.0.3 ping statistics ---
+10 packets transmitted, 9 received, 10% packet loss, time 9012ms
+rtt min/avg/max/mdev = 100.101/100.500/101.200/0.300 ms
+`,
+			rtt:  100500 * time.Microsecond,
+			loss: 10,
+		},
+		{
+			desc: "busybox",
+			output: `PING 10.1.0.3 (10.1.0.3): 56 data bytes
+
+--- 10.1.0.3 ping statistics ---
+10 packets transmitted, 10 packets received, 0% packet loss
+round-trip min/avg/max = 0.061/0.082/0.121 ms
+`,
+			rtt: 82 * time.Microsecond,
+		},
+		{
+			desc: "no replies",
+			output: `--- 10.1.0.3 ping statistics ---
+10 packets transmitted, 0 packets received, 100% packet loss
+`,
+			loss: 100,
+		},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			rtt, loss, err := ParsePing(tc.output)
+			require.NoError(t, err)
+			require.Equal(t, tc.rtt, rtt)
+			require.Equal(t, tc.loss, loss)
+		})
+	}
+	_, _, err := ParsePing("ping: bad address")
+	require.Error(t, err)
+}
+
+func TestToleranceCompare(t *testing.T) {
+	requested := Options{Latency: 100, BW: 1000}.Expected()
+	tolerance := Tolerance{RTT: 10 * time.Millisecond, Loss: 5, BW: 0.3}
+	require.Empty(t, tolerance.Compare(requested, Measurement{RTT: 105 * time.Millisecond, Upload: 800}))
+	require.Len(t, tolerance.Compare(requested, Measurement{RTT: time.Millisecond, Loss: 6, Upload: 5000}), 3)
+	// rtt can't be measured if everything is dropped
+	require.Len(t, tolerance.Compare(Options{PacketLoss: 100}.Expected(), Measurement{Loss: 100}), 0)
+}
+
+func TestThroughputStopsListener(t *testing.T) {
+	stopped := make(chan struct{})
+	dst := func(ctx context.Context, cmd []string) (string, error) {
+		<-ctx.Done()
+		close(stopped)
+		return "", ctx.Err()
+	}
+	src := func(ctx context.Context, cmd []string) (string, error) {
+		return "", errors.New("connection refused")
+	}
+	_, err := Throughput(context.Background(), src, dst, "10.0.0.2", 16)
+	require.Error(t, err)
+	select {
+	case <-stopped:
+	default:
+		t.Fatal("listener wasn't stopped")
+	}
+}
@@ -0,0 +1,107 @@
+package network
+
+import (
+	"encoding/csv"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Phase is a set of conditions that is applied for a duration.
+// Phase with zero duration is applied until profile is stopped.
+type Phase struct {
+	Options  Options
+	Duration time.Duration
+}
+
+// Profile is a schedule of conditions, e.g. LTE for 2m, then EDGE for 30s, then offline for 10s.
+type Profile struct {
+	Phases []Phase
+	// Loop restarts profile after last phase is finished.
+	Loop bool
+}
+
+func (p Profile) Validate() error {
+	if len(p.Phases) == 0 {
+		return fmt.Errorf("profile must have at least one phase")
+	}
+	for i, phase := range p.Phases {
+		if phase.Duration < 0 {
+			return fmt.Errorf("duration of phase %d is negative", i)
+		}
+		if phase.Duration == 0 && (i != len(p.Phases)-1 || p.Loop) {
+			return fmt.Errorf("only last phase of a profile without loop can have zero duration")
+		}
+	}
+	return nil
+}
+
+// ReadTrace reads profile from csv with timestamp in seconds, bandwidth in kb, delay in milliseconds
+// and loss in percents, e.g. 1.5,1000,80,2. Every row is applied until timestamp of the next row,
+// last row is applied until profile is stopped. Zero value means that the condition is not applied.
+// Timestamps are relative to the start of the profile, if the first row starts later a phase
+// without conditions is applied until it.
+func ReadTrace(r io.Reader) (Profile, error) {
+	reader := csv.NewReader(r)
+	reader.Comment = '#'
+	reader.FieldsPerRecord = 4
+	reader.TrimLeadingSpace = true
+	var (
+		profile    Profile
+		timestamps []time.Duration
+	)
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return profile, err
+		}
+		ts, err := strconv.ParseFloat(strings.TrimSpace(record[0]), 64)
+		if err != nil {
+			return profile, fmt.Errorf("invalid timestamp %s: %v", record[0], err)
+		}
+		var values [3]int
+		for i := range values {
+			values[i], err = strconv.Atoi(strings.TrimSpace(record[i+1]))
+			if err != nil {
+				return profile, fmt.Errorf("invalid value %s: %v", record[i+1], err)
+			}
+		}
+		timestamp := time.Duration(ts * float64(time.Second))
+		if timestamp < 0 {
+			return profile, fmt.Errorf("timestamp %v is negative", timestamp)
+		}
+		if len(timestamps) == 0 && timestamp > 0 {
+			timestamps = append(timestamps, 0)
+			profile.Phases = append(profile.Phases, Phase{})
+		}
+		if len(timestamps) != 0 && timestamp <= timestamps[len(timestamps)-1] {
+			return profile, fmt.Errorf("timestamps must be increasing, got %v after %v", timestamp, timestamps[len(timestamps)-1])
+		}
+		timestamps = append(timestamps, timestamp)
+		profile.Phases = append(profile.Phases, Phase{Options: Options{
+			BW:         values[0],
+			Latency:    values[1],
+			PacketLoss: values[2],
+		}})
+	}
+	for i := 0; i < len(timestamps)-1; i++ {
+		profile.Phases[i].Duration = timestamps[i+1] - timestamps[i]
+	}
+	return profile, profile.Validate()
+}
+
+// LoadTrace reads profile from a csv file.
+func LoadTrace(path string) (Profile, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return Profile{}, err
+	}
+	defer f.Close()
+	return ReadTrace(f)
+}
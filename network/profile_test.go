@@ -0,0 +1,40 @@
+package network
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestReadTrace(t *testing.T) {
+	p, err := ReadTrace(strings.NewReader(`
+# timestamp, bandwidth, delay, loss
+0, 1000, 50, 0
+1.5, 200, 300, 5
+10, 0, 0, 100
+`))
+	require.NoError(t, err)
+	require.Equal(t, []Phase{
+		{Options: Options{BW: 1000, Latency: 50}, Duration: 1500 * time.Millisecond},
+		{Options: Options{BW: 200, Latency: 300, PacketLoss: 5}, Duration: 8500 * time.Millisecond},
+		{Options: Options{PacketLoss: 100}},
+	}, p.Phases)
+
+	_, err = ReadTrace(strings.NewReader("2,0,0,0\n1,0,0,0\n"))
+	require.Error(t, err)
+
+	_, err = ReadTrace(strings.NewReader("-1,0,0,0\n"))
+	require.Error(t, err)
+}
+
+func TestReadTraceDelayedStart(t *testing.T) {
+	p, err := ReadTrace(strings.NewReader("5,1000,50,0\n8,0,0,100\n"))
+	require.NoError(t, err)
+	require.Equal(t, []Phase{
+		{Duration: 5 * time.Second},
+		{Options: Options{BW: 1000, Latency: 50}, Duration: 3 * time.Second},
+		{Options: Options{PacketLoss: 100}},
+	}, p.Phases)
+}
@@ -0,0 +1,474 @@
+package scenario
+
+import (
+	"context"
+	"crypto/elliptic"
+	"crypto/rand"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/ethereum/go-ethereum/crypto"
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/status-im/status-console-client/protocol/gethservice"
+
+	"github.com/status-im/status-scale/churn"
+	"github.com/status-im/status-scale/client"
+	"github.com/status-im/status-scale/cluster"
+	"github.com/status-im/status-scale/metrics"
+	"github.com/status-im/status-scale/topology"
+	"github.com/status-im/status-scale/utils"
+)
+
+var columns = map[string]func() []interface{}{
+	"p2p":        metrics.P2PColumns,
+	"discovery":  metrics.DiscoveryColumns,
+	"rendezvous": metrics.RendezvousColumns,
+	"peers":      metrics.OnlyPeers,
+	"envelopes":  metrics.Envelopes,
+}
+
+func NewRunner(c *cluster.Cluster, output io.Writer) Runner {
+	return Runner{cluster: c, output: output}
+}
+
+// Runner executes scenario against a cluster and writes results to output.
+type Runner struct {
+	cluster *cluster.Cluster
+	output  io.Writer
+}
+
+func (r Runner) Run(ctx context.Context, s Scenario) error {
+	if err := s.Validate(); err != nil {
+		return err
+	}
+	churnModels := make([]churn.SessionModel, len(s.Churn))
+	for i, c := range s.Churn {
+		var err error
+		churnModels[i], err = c.Model()
+		if err != nil {
+			return fmt.Errorf("failed to load churn model: %v", err)
+		}
+	}
+	r.images(s.Images)
+	log.Info("running scenario", "name", s.Name, "steps", len(s.Steps))
+	steps := s.Steps
+	// attached cluster was already deployed by the run that kept it
+	if r.cluster.Attached() {
+		log.Info("cluster is attached, steps are not deployed")
+		steps = nil
+	}
+	for i, step := range steps {
+		opts := step.ScaleOpts()
+		opts.NoDiscovery = s.Topology != nil
+		if step.Remove {
+			if err := r.cluster.Remove(ctx, opts); err != nil {
+				return fmt.Errorf("failed to remove peers in step %d: %v", i, err)
+			}
+			continue
+		}
+		if err := r.cluster.Create(ctx, opts); err != nil {
+			return fmt.Errorf("failed to deploy step %d: %v", i, err)
+		}
+	}
+	if s.Topology != nil {
+		if err := r.topology(ctx, *s.Topology); err != nil {
+			return err
+		}
+	}
+	if s.Regions != nil {
+		if err := r.regions(ctx, *s.Regions); err != nil {
+			return err
+		}
+	}
+	for _, cond := range s.Conditions {
+		if err := r.conditions(ctx, cond); err != nil {
+			return err
+		}
+	}
+	// targets must be without conditions, so all conditions are applied before verification
+	for _, cond := range s.Conditions {
+		if cond.Verify == nil {
+			continue
+		}
+		if err := r.verify(ctx, cond); err != nil {
+			return err
+		}
+	}
+	var (
+		rtt      *client.RTTMeter
+		contacts map[string][]gethservice.Contact
+	)
+	if s.Workload.Type == WorkloadRTT {
+		var err error
+		rtt, contacts, err = r.rtt(ctx, s.Workload)
+		if err != nil {
+			return err
+		}
+	}
+	var (
+		wg      sync.WaitGroup
+		cancel  = func() {}
+		results = make([]churnResult, len(s.Churn))
+	)
+	if len(s.Churn) != 0 {
+		var churnCtx context.Context
+		churnCtx, cancel = context.WithCancel(ctx)
+		for i := range s.Churn {
+			i := i
+			wg.Add(1)
+			go func() {
+				results[i] = r.churn(churnCtx, s.Churn[i], churnModels[i], contacts)
+				wg.Done()
+			}()
+		}
+	}
+	if len(s.Profiles) != 0 {
+		profilesCtx, cancelProfiles := context.WithCancel(ctx)
+		stopChurn := cancel
+		cancel = func() {
+			stopChurn()
+			cancelProfiles()
+		}
+		for _, p := range s.Profiles {
+			if err := r.profile(profilesCtx, p, &wg); err != nil {
+				cancel()
+				wg.Wait()
+				return err
+			}
+		}
+	}
+	start := time.Now()
+	if rtt != nil {
+		log.Debug("started metering latency")
+		if err := rtt.MeterFor(s.Duration.Duration); err != nil {
+			log.Error("metering latency failed", "error", err)
+		}
+	} else {
+		select {
+		case <-time.After(s.Duration.Duration):
+		case <-ctx.Done():
+		}
+	}
+	cancel()
+	wg.Wait()
+	fmt.Fprintf(r.output, "Scenario %s\n", s.Name)
+	fmt.Fprintf(r.output, "took %v\n\n", time.Since(start))
+	for i, c := range s.Churn {
+		byMode := map[churn.Mode][]churn.Transition{}
+		for _, t := range results[i].transitions {
+			byMode[t.Mode] = append(byMode[t.Mode], t)
+		}
+		fmt.Fprintf(r.output, "churn of %v\n", churnTypes(c))
+		for _, mode := range churn.Modes {
+			if len(byMode[mode]) == 0 {
+				continue
+			}
+			mean, max := churn.Lateness(byMode[mode])
+			fmt.Fprintf(r.output, "applied %d %s transitions, late by %v on average and %v at most\n",
+				len(byMode[mode]), mode, mean, max)
+		}
+		for _, rec := range results[i].recoveries {
+			fmt.Fprintf(r.output, "%s was offline for %v: %d messages recovered, %d received live\n",
+				rec.Peer, rec.Online.Sub(rec.Offline).Round(time.Millisecond), rec.Recovered, rec.Live)
+			if rec.RequestErr != nil {
+				fmt.Fprintf(r.output, "%s failed to request history: %v\n", rec.Peer, rec.RequestErr)
+			}
+		}
+		fmt.Fprintln(r.output)
+	}
+	if rtt != nil {
+		fmt.Fprintf(r.output, "metered rtt for %d messages\n\n", rtt.Messages())
+		for _, p := range []float64{75, 90, 95, 99.9} {
+			fmt.Fprintf(r.output, "latency for %v percentile: %v\n", p, rtt.Percentile(p))
+		}
+		fmt.Fprintln(r.output)
+	}
+	if s.Crawl != nil {
+		if err := r.crawl(ctx, *s.Crawl, s.Workload); err != nil {
+			return err
+		}
+	}
+	for _, m := range s.Metrics {
+		if err := r.metrics(ctx, m); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r Runner) images(images Images) {
+	if len(images.Statusd) != 0 {
+		r.cluster.Statusd = images.Statusd
+	}
+	if len(images.Client) != 0 {
+		r.cluster.Client = images.Client
+	}
+	if len(images.Bootnode) != 0 {
+		r.cluster.Bootnode = images.Bootnode
+	}
+	if len(images.Rendezvous) != 0 {
+		r.cluster.RendezvousBoot = images.Rendezvous
+	}
+}
+
+func (r Runner) users(typ cluster.PeerType) []*cluster.Client {
+	switch typ {
+	case cluster.User:
+		return r.cluster.GetUsers()
+	case cluster.MVDS:
+		return r.cluster.GetMVDSClients()
+	}
+	return nil
+}
+
+func (r Runner) relays(typ cluster.PeerType) []*cluster.Peer {
+	switch typ {
+	case cluster.Relay:
+		return r.cluster.GetRelays()
+	case cluster.Mail:
+		return r.cluster.GetMails()
+	}
+	return nil
+}
+
+// peers returns all status-go peers of the given types. If types are empty all peers will be returned.
+func (r Runner) peers(types []cluster.PeerType) (users []*cluster.Client, relays []*cluster.Peer) {
+	if len(types) == 0 {
+		types = statusTypes
+	}
+	for _, typ := range types {
+		users = append(users, r.users(typ)...)
+		relays = append(relays, r.relays(typ)...)
+	}
+	return users, relays
+}
+
+func (r Runner) topology(ctx context.Context, t Topology) error {
+	types := t.Types
+	if len(types) == 0 {
+		types = []cluster.PeerType{cluster.Relay}
+	}
+	peers := r.cluster.GetPeers(types...)
+	g, err := t.Build(len(peers))
+	if err != nil {
+		return err
+	}
+	log.Info("applying topology", "type", t.Type, "peers", len(peers), "edges", len(g.Edges()))
+	return topology.Apply(ctx, g, peers)
+}
+
+func (r Runner) crawl(ctx context.Context, c Crawl, w Workload) error {
+	peers := r.cluster.GetPeers(c.Types...)
+	g, err := topology.Crawl(ctx, peers)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(r.output, "crawled %d nodes with %d connections\n", g.Len(), len(g.Edges()))
+	fmt.Fprintf(r.output, "diameter: %d\n", g.Diameter())
+	fmt.Fprintf(r.output, "connected components: %d\n", len(g.Components()))
+	degrees := g.DegreeDistribution()
+	keys := make([]int, 0, len(degrees))
+	for degree := range degrees {
+		keys = append(keys, degree)
+	}
+	sort.Ints(keys)
+	for _, degree := range keys {
+		fmt.Fprintf(r.output, "nodes with degree %d: %d\n", degree, degrees[degree])
+	}
+	if w.Type == WorkloadRTT {
+		hops, err := topology.HopDistance(g, r.cluster.GetUser(w.Sender).Peer, r.cluster.GetUser(w.Receiver).Peer)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(r.output, "hops between sender and receiver: %d\n", hops)
+	}
+	fmt.Fprintln(r.output)
+	if len(c.DOT) != 0 {
+		if err := writeGraph(c.DOT, g, topology.WriteDOT); err != nil {
+			return err
+		}
+	}
+	if len(c.GraphML) != 0 {
+		if err := writeGraph(c.GraphML, g, topology.WriteGraphML); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func writeGraph(path string, g *topology.Graph, write func(io.Writer, *topology.Graph) error) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return write(f, g)
+}
+
+func (r Runner) conditions(ctx context.Context, cond Conditions) error {
+	nodes := r.cluster.GetNodes(cond.Types...)
+	opts := cond.Options(r.cluster.IPAM.String())
+	group := utils.NewGroup(ctx, len(nodes))
+	for i := range nodes {
+		n := nodes[i]
+		group.Run(func(ctx context.Context) error {
+			return n.EnableConditions(ctx, opts)
+		})
+	}
+	return group.Error()
+}
+
+func (r Runner) verify(ctx context.Context, cond Conditions) error {
+	nodes := r.cluster.GetNodes(cond.Types...)
+	if len(nodes) == 0 {
+		return nil
+	}
+	v, err := r.cluster.VerifyConditions(ctx, nodes[0], cond.Options(r.cluster.IPAM.String()), cond.Verify.Tolerance())
+	if err != nil {
+		return fmt.Errorf("failed to verify conditions: %v", err)
+	}
+	fmt.Fprintf(r.output, "verified conditions %v\n", v)
+	return v.Err()
+}
+
+// profile replays profile on every node in background until context is cancelled.
+func (r Runner) profile(ctx context.Context, p Profile, wg *sync.WaitGroup) error {
+	profile, err := p.Profile(r.cluster.IPAM.String())
+	if err != nil {
+		return err
+	}
+	nodes := r.cluster.GetNodes(p.Types...)
+	log.Info("replaying network profile", "phases", len(profile.Phases), "nodes", len(nodes))
+	for i := range nodes {
+		n := nodes[i]
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := cluster.Replay(ctx, n, profile); err != nil {
+				log.Error("network profile failed", "node", n.UID(), "error", err)
+			}
+		}()
+	}
+	return nil
+}
+
+func (r Runner) regions(ctx context.Context, regions Regions) error {
+	matrix, err := regions.Matrix()
+	if err != nil {
+		return err
+	}
+	assigned := map[string]bool{}
+	nodes := map[string][]cluster.Node{}
+	for _, p := range regions.Peers {
+		candidates := r.cluster.GetNodes(p.Types...)
+		count := len(candidates)
+		if p.Ratio != 0 {
+			count = int(math.Round(float64(len(candidates)) * p.Ratio))
+		}
+		for _, n := range candidates {
+			if count == 0 {
+				break
+			}
+			if assigned[n.UID()] {
+				continue
+			}
+			assigned[n.UID()] = true
+			nodes[p.Region] = append(nodes[p.Region], n)
+			count--
+		}
+	}
+	return r.cluster.ApplyRegions(ctx, matrix, nodes)
+}
+
+func churnTypes(c Churn) []cluster.PeerType {
+	if len(c.Types) == 0 {
+		return []cluster.PeerType{cluster.User}
+	}
+	return c.Types
+}
+
+type churnResult struct {
+	transitions []churn.Transition
+	recoveries  []churn.Recovery
+}
+
+func (r Runner) churn(ctx context.Context, params Churn, model churn.SessionModel, contacts map[string][]gethservice.Contact) churnResult {
+	history := churn.History{Contacts: contacts}
+	if params.History != nil {
+		history.Strategy = params.History.Strategy
+		history.Margin = params.History.Margin.Duration
+	}
+	nodes := r.cluster.GetNodes(churnTypes(params)...)
+	if params.Ratio != 0 {
+		nodes = nodes[:int(math.Round(float64(len(nodes))*params.Ratio))]
+	}
+	log.Info("simulating churn", "types", churnTypes(params), "nodes", len(nodes))
+	sim := churn.NewChurnSim(nodes, churn.Params{
+		TargetAddrs: []string{r.cluster.IPAM.String()},
+		Period:      params.Period.Duration,
+		ChurnRate:   params.Rate,
+		Modes:       params.Modes,
+		Model:       model,
+		Seed:        params.Seed,
+		History:     history,
+	})
+	if err := sim.Run(ctx); err != nil {
+		log.Error("churn simulation failed", "error", err)
+	}
+	return churnResult{transitions: sim.Transitions(), recoveries: sim.Recoveries()}
+}
+
+// rtt creates a private chat between sender and receiver and returns the meter with contacts of both users by uid.
+func (r Runner) rtt(ctx context.Context, w Workload) (*client.RTTMeter, map[string][]gethservice.Contact, error) {
+	var (
+		sender   = r.cluster.GetUser(w.Sender)
+		receiver = r.cluster.GetUser(w.Receiver)
+	)
+	if sender == nil || receiver == nil {
+		return nil, nil, fmt.Errorf("users %d and %d must be running", w.Sender, w.Receiver)
+	}
+	name := make([]byte, 10)
+	if _, err := rand.Read(name); err != nil {
+		return nil, nil, err
+	}
+	var (
+		senderKey   hexutil.Bytes = elliptic.Marshal(crypto.S256(), sender.Identity.PublicKey.X, sender.Identity.PublicKey.Y)
+		receiverKey hexutil.Bytes = elliptic.Marshal(crypto.S256(), receiver.Identity.PublicKey.X, receiver.Identity.PublicKey.Y)
+		chat0                     = gethservice.Contact{Name: hexutil.Encode(name), PublicKey: receiverKey}
+		chat1                     = gethservice.Contact{Name: hexutil.Encode(name), PublicKey: senderKey}
+	)
+	if err := client.ChatClient(sender.Rpc()).AddContact(ctx, chat0); err != nil {
+		return nil, nil, fmt.Errorf("failed to add contact to %s: %v", sender.UID(), err)
+	}
+	if err := client.ChatClient(receiver.Rpc()).AddContact(ctx, chat1); err != nil {
+		return nil, nil, fmt.Errorf("failed to add contact to %s: %v", receiver.UID(), err)
+	}
+	contacts := map[string][]gethservice.Contact{
+		sender.UID():   {chat0},
+		receiver.UID(): {chat1},
+	}
+	return client.NewRTTMeter(chat0, sender, receiver), contacts, nil
+}
+
+func (r Runner) metrics(ctx context.Context, m Metrics) error {
+	sets := make([][]interface{}, 0, len(m.Columns))
+	for _, name := range m.Columns {
+		sets = append(sets, columns[name]())
+	}
+	table := metrics.NewCompleteTab("container name", sets...)
+	users, relays := r.peers(m.Types)
+	ctx, cancel := context.WithTimeout(ctx, 10*time.Second)
+	defer cancel()
+	if err := client.CollectMetrics(ctx, table, users, relays); err != nil {
+		return fmt.Errorf("failed to collect metrics: %v", err)
+	}
+	metrics.ToASCII(table, r.output).Render()
+	fmt.Fprintln(r.output)
+	return nil
+}
@@ -0,0 +1,718 @@
+package scenario
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"time"
+
+	yaml "gopkg.in/yaml.v2"
+
+	"github.com/status-im/status-go/params"
+	"github.com/status-im/status-scale/churn"
+	"github.com/status-im/status-scale/cluster"
+	"github.com/status-im/status-scale/network"
+	"github.com/status-im/status-scale/topology"
+)
+
+// Duration is a time.Duration that is decoded from strings like "10s" or "1m30s".
+type Duration struct {
+	time.Duration
+}
+
+func (d *Duration) parse(s string) (err error) {
+	d.Duration, err = time.ParseDuration(s)
+	return err
+}
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var s string
+	if err := json.Unmarshal(data, &s); err != nil {
+		return err
+	}
+	return d.parse(s)
+}
+
+func (d *Duration) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var s string
+	if err := unmarshal(&s); err != nil {
+		return err
+	}
+	return d.parse(s)
+}
+
+// Patch is a json object that can be written in yaml as a regular mapping.
+type Patch json.RawMessage
+
+func (p *Patch) UnmarshalJSON(data []byte) error {
+	*p = append((*p)[:0], data...)
+	return nil
+}
+
+func (p *Patch) UnmarshalYAML(unmarshal func(interface{}) error) error {
+	var v interface{}
+	if err := unmarshal(&v); err != nil {
+		return err
+	}
+	data, err := json.Marshal(jsonValue(v))
+	if err != nil {
+		return err
+	}
+	*p = data
+	return nil
+}
+
+// jsonValue converts yaml mappings with interface{} keys to mappings that can be encoded to json.
+func jsonValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		rst := make(map[string]interface{}, len(v))
+		for key, value := range v {
+			rst[fmt.Sprint(key)] = jsonValue(value)
+		}
+		return rst
+	case []interface{}:
+		for i := range v {
+			v[i] = jsonValue(v[i])
+		}
+	}
+	return v
+}
+
+// validate checks that patch can be merged into status-go config and has no unknown fields.
+func (p Patch) validate() error {
+	dec := json.NewDecoder(bytes.NewReader(p))
+	dec.DisallowUnknownFields()
+	return dec.Decode(&params.NodeConfig{})
+}
+
+// Images overwrite images that were provided to the cluster.
+type Images struct {
+	Statusd    string `json:"statusd" yaml:"statusd"`
+	Client     string `json:"client" yaml:"client"`
+	Bootnode   string `json:"bootnode" yaml:"bootnode"`
+	Rendezvous string `json:"rendezvous" yaml:"rendezvous"`
+}
+
+// Step is a single batch of peers that will be added to the cluster, or removed from it
+// if Remove is true. Steps are executed one after another, e.g. users must be deployed after mail servers.
+type Step struct {
+	Remove     bool `json:"remove" yaml:"remove"`
+	Boot       int  `json:"boot" yaml:"boot"`
+	Relay      int  `json:"relay" yaml:"relay"`
+	Users      int  `json:"users" yaml:"users"`
+	MVDS       int  `json:"mvds" yaml:"mvds"`
+	Rendezvous int  `json:"rendezvous" yaml:"rendezvous"`
+	Mails      int  `json:"mails" yaml:"mails"`
+	// Images overwrite scenario images for a fraction of peers in this step.
+	Images map[cluster.PeerType]cluster.Image `json:"images" yaml:"images"`
+	// NAT puts users and mvds peers of this step behind nat gateways.
+	NAT *NAT `json:"nat" yaml:"nat"`
+	// NodeConfig is merged into status-go config of every peer of the type in this step.
+	NodeConfig map[cluster.PeerType]Patch `json:"node_config" yaml:"node_config"`
+}
+
+// NAT type is either port-restricted (default) or symmetric. If PeersPerGateway is zero
+// all peers in the step share one gateway.
+type NAT struct {
+	Type            cluster.NATType `json:"type" yaml:"type"`
+	PeersPerGateway int             `json:"peers_per_gateway" yaml:"peers_per_gateway"`
+}
+
+func (s Step) ScaleOpts() cluster.ScaleOpts {
+	var nat *cluster.NATOpts
+	if s.NAT != nil {
+		nat = &cluster.NATOpts{Type: s.NAT.Type, PeersPerGateway: s.NAT.PeersPerGateway}
+	}
+	return cluster.ScaleOpts{
+		NAT:        nat,
+		Boot:       s.Boot,
+		Relay:      s.Relay,
+		Users:      s.Users,
+		MVDS:       s.MVDS,
+		Rendezvous: s.Rendezvous,
+		Mails:      s.Mails,
+		Deploy:     true,
+		Images:     s.Images,
+		Templates:  s.templates(),
+	}
+}
+
+func (s Step) templates() map[cluster.PeerType]cluster.Template {
+	if len(s.NodeConfig) == 0 {
+		return nil
+	}
+	rst := map[cluster.PeerType]cluster.Template{}
+	for typ, patch := range s.NodeConfig {
+		patch := json.RawMessage(patch)
+		rst[typ] = func(cfg *cluster.PeerConfig) {
+			cfg.NodeConfigPatch = patch
+		}
+	}
+	return rst
+}
+
+// Conditions are applied to every peer of the listed types.
+// If TargetAddrs are empty whole cluster cidr is used. Non-zero values overwrite values from the preset.
+type Conditions struct {
+	Types       []cluster.PeerType `json:"types" yaml:"types"`
+	Preset      string             `json:"preset" yaml:"preset"`
+	TargetAddrs []string           `json:"target_addrs" yaml:"target_addrs"`
+	Latency     int                `json:"latency" yaml:"latency"`
+	PacketLoss  int                `json:"packet_loss" yaml:"packet_loss"`
+	BW          int                `json:"bw" yaml:"bw"`
+	// Jitter, reordering, duplication and corruption are supported only by netem.
+	Jitter       int    `json:"jitter" yaml:"jitter"`
+	Distribution string `json:"distribution" yaml:"distribution"`
+	Reorder      int    `json:"reorder" yaml:"reorder"`
+	Duplicate    int    `json:"duplicate" yaml:"duplicate"`
+	Corrupt      int    `json:"corrupt" yaml:"corrupt"`
+	// Download conditions are applied to traffic received from target addrs.
+	DownloadLatency    int `json:"download_latency" yaml:"download_latency"`
+	DownloadPacketLoss int `json:"download_packet_loss" yaml:"download_packet_loss"`
+	DownloadBW         int `json:"download_bw" yaml:"download_bw"`
+	// Verify probes conditions after they are applied and fails the run if they are not effective.
+	Verify *Verify `json:"verify" yaml:"verify"`
+}
+
+// Verify probes from the first peer of the types to a peer without conditions in target addrs.
+// Zero values are replaced with network.DefaultTolerance.
+type Verify struct {
+	RTT  int     `json:"rtt" yaml:"rtt"`   // milliseconds
+	Loss float64 `json:"loss" yaml:"loss"` // percentage points
+	BW   float64 `json:"bw" yaml:"bw"`     // fraction of requested rate
+}
+
+func (v Verify) Tolerance() network.Tolerance {
+	tolerance := network.DefaultTolerance
+	if v.RTT != 0 {
+		tolerance.RTT = time.Duration(v.RTT) * time.Millisecond
+	}
+	if v.Loss != 0 {
+		tolerance.Loss = v.Loss
+	}
+	if v.BW != 0 {
+		tolerance.BW = v.BW
+	}
+	return tolerance
+}
+
+func (c Conditions) Options(cidr string) network.Options {
+	opts := network.Presets[c.Preset].Options
+	opts.TargetAddrs = c.TargetAddrs
+	if len(opts.TargetAddrs) == 0 {
+		opts.TargetAddrs = []string{cidr}
+	}
+	overwrite := func(dst *int, value int) {
+		if value != 0 {
+			*dst = value
+		}
+	}
+	overwrite(&opts.Latency, c.Latency)
+	overwrite(&opts.PacketLoss, c.PacketLoss)
+	overwrite(&opts.BW, c.BW)
+	overwrite(&opts.Jitter, c.Jitter)
+	overwrite(&opts.Reorder, c.Reorder)
+	overwrite(&opts.Duplicate, c.Duplicate)
+	overwrite(&opts.Corrupt, c.Corrupt)
+	overwrite(&opts.DownloadLatency, c.DownloadLatency)
+	overwrite(&opts.DownloadPacketLoss, c.DownloadPacketLoss)
+	overwrite(&opts.DownloadBW, c.DownloadBW)
+	if len(c.Distribution) != 0 {
+		opts.Distribution = c.Distribution
+	}
+	return opts
+}
+
+func (c Conditions) validate() error {
+	if c.Verify != nil && (c.Verify.RTT < 0 || c.Verify.Loss < 0 || c.Verify.BW < 0) {
+		return errors.New("verify tolerance can't be negative")
+	}
+	if len(c.Preset) == 0 {
+		return nil
+	}
+	if _, exist := network.Presets[c.Preset]; !exist {
+		return fmt.Errorf("unknown network preset %s, known presets: %s", c.Preset, strings.Join(network.PresetNames(), ", "))
+	}
+	return nil
+}
+
+// Profile replays a schedule of conditions on every peer of the listed types while workload is running.
+// Phases can be loaded from a csv trace with rows of timestamp, bandwidth, delay and loss instead,
+// no conditions are applied before the first timestamp.
+// If TargetAddrs are empty whole cluster cidr is used.
+type Profile struct {
+	Types       []cluster.PeerType `json:"types" yaml:"types"`
+	TargetAddrs []string           `json:"target_addrs" yaml:"target_addrs"`
+	Phases      []Phase            `json:"phases" yaml:"phases"`
+	Trace       string             `json:"trace" yaml:"trace"`
+	Loop        bool               `json:"loop" yaml:"loop"`
+}
+
+// Phase is applied for a duration, last phase without duration is applied until scenario is finished.
+type Phase struct {
+	Duration   Duration `json:"duration" yaml:"duration"`
+	Conditions `yaml:",inline"`
+}
+
+// Profile builds network profile. TargetAddrs of the profile are used for phases without them.
+func (p Profile) Profile(cidr string) (network.Profile, error) {
+	targets := p.TargetAddrs
+	if len(targets) == 0 {
+		targets = []string{cidr}
+	}
+	var (
+		profile network.Profile
+		err     error
+	)
+	if len(p.Trace) != 0 {
+		profile, err = network.LoadTrace(p.Trace)
+		if err != nil {
+			return profile, err
+		}
+	}
+	for _, phase := range p.Phases {
+		profile.Phases = append(profile.Phases, network.Phase{
+			Options:  phase.Options(cidr),
+			Duration: phase.Duration.Duration,
+		})
+	}
+	for i := range profile.Phases {
+		if len(p.Trace) != 0 || len(p.Phases[i].TargetAddrs) == 0 {
+			profile.Phases[i].Options.TargetAddrs = targets
+		}
+	}
+	profile.Loop = p.Loop
+	return profile, profile.Validate()
+}
+
+// Regions spread peers across regions with latency between them. RTT is a matrix of round trip times
+// in milliseconds, it can be loaded from a csv file with rows like "eu,us,90" instead.
+type Regions struct {
+	RTT   network.LatencyMatrix `json:"rtt" yaml:"rtt"`
+	Path  string                `json:"path" yaml:"path"`
+	Peers []RegionPeers         `json:"peers" yaml:"peers"`
+}
+
+// RegionPeers assigns a fraction of peers of the listed types to the region.
+// Peers are assigned in order, if ratio is zero all peers that are left are assigned.
+type RegionPeers struct {
+	Region string             `json:"region" yaml:"region"`
+	Types  []cluster.PeerType `json:"types" yaml:"types"`
+	Ratio  float64            `json:"ratio" yaml:"ratio"`
+}
+
+// Matrix returns inline matrix or loads it from the file.
+func (r Regions) Matrix() (network.LatencyMatrix, error) {
+	if len(r.Path) != 0 {
+		return network.LoadLatencyMatrix(r.Path)
+	}
+	return r.RTT, r.RTT.Validate()
+}
+
+// Topology connects peers of the listed types according to the graph.
+// If topology is set discovery is disabled for all status-go peers.
+type Topology struct {
+	topology.Spec `yaml:",inline"`
+	Types         []cluster.PeerType `json:"types" yaml:"types"`
+}
+
+// Crawl collects graph of connections between peers after workload is finished.
+// Graph can be exported to DOT and GraphML files.
+type Crawl struct {
+	Types   []cluster.PeerType `json:"types" yaml:"types"`
+	DOT     string             `json:"dot" yaml:"dot"`
+	GraphML string             `json:"graphml" yaml:"graphml"`
+}
+
+// Churn enables churn simulation for peers of the listed types, users by default. Ratio is a fraction of peers
+// that churn, all peers if zero. Sessions are generated either from rate and period, from online
+// and offline distributions, from a diurnal model, or replayed from a csv trace with rows of user index,
+// start and end of an online session in seconds.
+type Churn struct {
+	Types   []cluster.PeerType `json:"types" yaml:"types"`
+	Ratio   float64            `json:"ratio" yaml:"ratio"`
+	Rate    float64            `json:"rate" yaml:"rate"`
+	Period  Duration           `json:"period" yaml:"period"`
+	Online  *Distribution      `json:"online" yaml:"online"`
+	Offline *Distribution      `json:"offline" yaml:"offline"`
+	Diurnal *Diurnal           `json:"diurnal" yaml:"diurnal"`
+	Trace   string             `json:"trace" yaml:"trace"`
+	// Seed makes sessions reproducible.
+	Seed int64 `json:"seed" yaml:"seed"`
+	// Modes are assigned to peers in order: blackhole (default), pause, stop or kill.
+	Modes []churn.Mode `json:"modes" yaml:"modes"`
+	// History configures how users request missed messages when they are back online.
+	History *History `json:"history" yaml:"history"`
+}
+
+// History strategy is all (default), targeted or none. Targeted strategy requests messages for every contact
+// of the workload since user went offline, minus margin.
+type History struct {
+	Strategy churn.Strategy `json:"strategy" yaml:"strategy"`
+	Margin   Duration       `json:"margin" yaml:"margin"`
+}
+
+// Distribution is exponential with mean, or weibull or pareto with shape and scale.
+type Distribution struct {
+	Type  string   `json:"type" yaml:"type"`
+	Mean  Duration `json:"mean" yaml:"mean"`
+	Shape float64  `json:"shape" yaml:"shape"`
+	Scale Duration `json:"scale" yaml:"scale"`
+}
+
+func (d Distribution) Distribution() (churn.Distribution, error) {
+	var rst churn.Distribution
+	switch d.Type {
+	case "exponential":
+		rst = churn.Exponential{Mean: d.Mean.Duration}
+	case "weibull":
+		rst = churn.Weibull{Shape: d.Shape, Scale: d.Scale.Duration}
+	case "pareto":
+		rst = churn.Pareto{Shape: d.Shape, Scale: d.Scale.Duration}
+	default:
+		return nil, fmt.Errorf("unknown distribution %s", d.Type)
+	}
+	return rst, rst.Validate()
+}
+
+// Diurnal changes fraction of online users from min to max at peak during a simulated day.
+type Diurnal struct {
+	Day     Duration `json:"day" yaml:"day"`
+	Peak    Duration `json:"peak" yaml:"peak"`
+	Min     float64  `json:"min" yaml:"min"`
+	Max     float64  `json:"max" yaml:"max"`
+	Session Duration `json:"session" yaml:"session"`
+}
+
+// Model returns session model, nil if rate and period are used.
+func (c Churn) Model() (churn.SessionModel, error) {
+	switch {
+	case len(c.Trace) != 0:
+		trace, err := churn.LoadTrace(c.Trace)
+		if err != nil {
+			return nil, err
+		}
+		return trace, nil
+	case c.Diurnal != nil:
+		return churn.NewDiurnal(churn.Diurnal{
+			Day:     c.Diurnal.Day.Duration,
+			Peak:    c.Diurnal.Peak.Duration,
+			Min:     c.Diurnal.Min,
+			Max:     c.Diurnal.Max,
+			Session: c.Diurnal.Session.Duration,
+		}, c.Seed)
+	case c.Online != nil:
+		online, err := c.Online.Distribution()
+		if err != nil {
+			return nil, err
+		}
+		offline, err := c.Offline.Distribution()
+		if err != nil {
+			return nil, err
+		}
+		return churn.NewSessions(online, offline, c.Seed)
+	}
+	return nil, nil
+}
+
+func (c Churn) validate() error {
+	if c.Ratio < 0 || c.Ratio > 1 {
+		return fmt.Errorf("churn ratio must be in [0, 1], got %v", c.Ratio)
+	}
+	if c.History != nil && len(c.History.Strategy) != 0 {
+		known := false
+		for _, strategy := range churn.Strategies {
+			known = known || strategy == c.History.Strategy
+		}
+		if !known {
+			return fmt.Errorf("unknown history strategy %s", c.History.Strategy)
+		}
+	}
+	for _, mode := range c.Modes {
+		known := false
+		for _, m := range churn.Modes {
+			known = known || m == mode
+		}
+		if !known {
+			return fmt.Errorf("unknown churn mode %s", mode)
+		}
+	}
+	models := 0
+	for _, set := range []bool{c.Rate != 0 || c.Period.Duration != 0, c.Online != nil || c.Offline != nil, c.Diurnal != nil, len(c.Trace) != 0} {
+		if set {
+			models++
+		}
+	}
+	if models != 1 {
+		return errors.New("churn requires exactly one of rate and period, online and offline, diurnal or trace")
+	}
+	switch {
+	case c.Online != nil || c.Offline != nil:
+		if c.Online == nil || c.Offline == nil {
+			return errors.New("churn requires both online and offline distributions")
+		}
+	case c.Diurnal != nil || len(c.Trace) != 0:
+	default:
+		if c.Rate <= 0 || c.Rate > 1 {
+			return fmt.Errorf("churn rate must be in (0, 1], got %v", c.Rate)
+		}
+		if c.Period.Duration < 2*time.Second {
+			return fmt.Errorf("churn period must be at least 2s, got %v", c.Period)
+		}
+		return nil
+	}
+	// trace is loaded only when scenario is executed
+	if len(c.Trace) != 0 {
+		return nil
+	}
+	_, err := c.Model()
+	return err
+}
+
+const (
+	WorkloadRTT = "rtt"
+)
+
+// Workload describes load that is generated while scenario is running.
+type Workload struct {
+	Type     string `json:"type" yaml:"type"`
+	Sender   int    `json:"sender" yaml:"sender"`
+	Receiver int    `json:"receiver" yaml:"receiver"`
+}
+
+// Metrics describes a table that will be collected after workload is finished.
+type Metrics struct {
+	Columns []string           `json:"columns" yaml:"columns"`
+	Types   []cluster.PeerType `json:"types" yaml:"types"`
+}
+
+type Scenario struct {
+	Name       string       `json:"name" yaml:"name"`
+	Images     Images       `json:"images" yaml:"images"`
+	Steps      []Step       `json:"steps" yaml:"steps"`
+	Topology   *Topology    `json:"topology" yaml:"topology"`
+	Regions    *Regions     `json:"regions" yaml:"regions"`
+	Conditions []Conditions `json:"conditions" yaml:"conditions"`
+	Profiles   []Profile    `json:"profiles" yaml:"profiles"`
+	Churn      []Churn      `json:"churn" yaml:"churn"`
+	Workload   Workload     `json:"workload" yaml:"workload"`
+	Crawl      *Crawl       `json:"crawl" yaml:"crawl"`
+	Metrics    []Metrics    `json:"metrics" yaml:"metrics"`
+	Duration   Duration     `json:"duration" yaml:"duration"`
+}
+
+var (
+	// statusTypes are types of peers that run status-go.
+	statusTypes = []cluster.PeerType{cluster.Relay, cluster.Mail, cluster.User, cluster.MVDS}
+	allTypes    = cluster.AllTypes
+)
+
+// Load reads scenario from a file. Format is selected based on file extension.
+// Relative paths to traces, topology and regions are resolved against directory of the file.
+func Load(path string) (Scenario, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Scenario{}, err
+	}
+	var s Scenario
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".json":
+		dec := json.NewDecoder(bytes.NewReader(data))
+		dec.DisallowUnknownFields()
+		err = dec.Decode(&s)
+	case ".yaml", ".yml":
+		err = yaml.UnmarshalStrict(data, &s)
+	default:
+		return s, fmt.Errorf("unknown scenario format %s", ext)
+	}
+	if err != nil {
+		return s, fmt.Errorf("failed to decode scenario %s: %v", path, err)
+	}
+	s.resolve(filepath.Dir(path))
+	return s, s.Validate()
+}
+
+// resolve joins relative paths in the scenario with dir.
+func (s *Scenario) resolve(dir string) {
+	join := func(path *string) {
+		if len(*path) != 0 && !filepath.IsAbs(*path) {
+			*path = filepath.Join(dir, *path)
+		}
+	}
+	for i := range s.Profiles {
+		join(&s.Profiles[i].Trace)
+	}
+	for i := range s.Churn {
+		join(&s.Churn[i].Trace)
+	}
+	if s.Topology != nil {
+		join(&s.Topology.Path)
+	}
+	if s.Regions != nil {
+		join(&s.Regions.Path)
+	}
+}
+
+// peerTypes validates that every peer type is known and supported by the operation.
+func peerTypes(types []cluster.PeerType, supported ...cluster.PeerType) error {
+	for _, typ := range types {
+		found := false
+		for _, other := range supported {
+			if typ == other {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return fmt.Errorf("peer type %s is not supported", typ)
+		}
+	}
+	return nil
+}
+
+func (s Scenario) Validate() error {
+	if len(s.Steps) == 0 {
+		return errors.New("scenario must have at least one step")
+	}
+	users := 0
+	for _, step := range s.Steps {
+		if step.Remove {
+			users -= step.Users
+		} else {
+			users += step.Users
+		}
+	}
+	if s.Topology != nil {
+		if err := peerTypes(s.Topology.Types, statusTypes...); err != nil {
+			return err
+		}
+		if s.Topology.Type == topology.TypeFile && len(s.Topology.Path) == 0 {
+			return errors.New("path is required for topology from file")
+		}
+	}
+	for _, step := range s.Steps {
+		for typ, img := range step.Images {
+			if err := peerTypes([]cluster.PeerType{typ}, allTypes...); err != nil {
+				return err
+			}
+			if img.Ratio < 0 || img.Ratio > 1 {
+				return fmt.Errorf("image ratio must be in [0, 1], got %v", img.Ratio)
+			}
+		}
+		if step.NAT != nil {
+			switch step.NAT.Type {
+			case "", cluster.NATPortRestricted, cluster.NATSymmetric:
+			default:
+				return fmt.Errorf("unknown nat type %s", step.NAT.Type)
+			}
+			if step.NAT.PeersPerGateway < 0 {
+				return fmt.Errorf("peers per gateway must not be negative, got %d", step.NAT.PeersPerGateway)
+			}
+		}
+		for typ, patch := range step.NodeConfig {
+			if err := peerTypes([]cluster.PeerType{typ}, statusTypes...); err != nil {
+				return err
+			}
+			if err := patch.validate(); err != nil {
+				return fmt.Errorf("invalid node config for %s: %v", typ, err)
+			}
+		}
+	}
+	if s.Regions != nil {
+		if len(s.Regions.RTT) != 0 && len(s.Regions.Path) != 0 {
+			return errors.New("regions rtt and path can't be used together")
+		}
+		if err := s.Regions.RTT.Validate(); err != nil {
+			return err
+		}
+		for _, p := range s.Regions.Peers {
+			if len(p.Region) == 0 {
+				return errors.New("region name is required")
+			}
+			if err := peerTypes(p.Types, allTypes...); err != nil {
+				return err
+			}
+			if p.Ratio < 0 || p.Ratio > 1 {
+				return fmt.Errorf("region ratio must be in [0, 1], got %v", p.Ratio)
+			}
+		}
+	}
+	for _, c := range s.Conditions {
+		if err := peerTypes(c.Types, allTypes...); err != nil {
+			return err
+		}
+		if err := c.validate(); err != nil {
+			return err
+		}
+	}
+	for _, p := range s.Profiles {
+		if err := peerTypes(p.Types, allTypes...); err != nil {
+			return err
+		}
+		if (len(p.Trace) == 0) == (len(p.Phases) == 0) {
+			return errors.New("profile requires either phases or trace")
+		}
+		for _, phase := range p.Phases {
+			if len(phase.Types) != 0 {
+				return errors.New("types can't be used in a profile phase")
+			}
+			if err := phase.validate(); err != nil {
+				return err
+			}
+		}
+		if len(p.Phases) != 0 {
+			if _, err := p.Profile(""); err != nil {
+				return err
+			}
+		}
+	}
+	for _, c := range s.Churn {
+		if err := peerTypes(c.Types, allTypes...); err != nil {
+			return err
+		}
+		if err := c.validate(); err != nil {
+			return err
+		}
+		// contacts are known only for the rtt workload
+		if c.History != nil && c.History.Strategy == churn.RequestTargeted && s.Workload.Type != WorkloadRTT {
+			return errors.New("targeted history requests require rtt workload")
+		}
+	}
+	switch s.Workload.Type {
+	case "":
+	case WorkloadRTT:
+		if s.Workload.Sender == s.Workload.Receiver {
+			return errors.New("rtt sender and receiver must be different users")
+		}
+		if s.Workload.Sender >= users || s.Workload.Receiver >= users {
+			return fmt.Errorf("rtt workload requires users %d and %d, scenario has only %d",
+				s.Workload.Sender, s.Workload.Receiver, users)
+		}
+		if s.Duration.Duration == 0 {
+			return errors.New("duration is required for rtt workload")
+		}
+	default:
+		return fmt.Errorf("unknown workload %s", s.Workload.Type)
+	}
+	if s.Crawl != nil {
+		if err := peerTypes(s.Crawl.Types, statusTypes...); err != nil {
+			return err
+		}
+	}
+	for _, m := range s.Metrics {
+		if err := peerTypes(m.Types, statusTypes...); err != nil {
+			return err
+		}
+		for _, name := range m.Columns {
+			if _, exist := columns[name]; !exist {
+				return fmt.Errorf("unknown metrics columns %s", name)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,127 @@
+package scenario
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/status-im/status-scale/churn"
+	"github.com/status-im/status-scale/cluster"
+	"github.com/status-im/status-scale/network"
+)
+
+func TestLoadExamples(t *testing.T) {
+	paths, err := filepath.Glob("../scenarios/*")
+	require.NoError(t, err)
+	require.NotEmpty(t, paths)
+	for _, path := range paths {
+		switch filepath.Ext(path) {
+		case ".yaml", ".json":
+		default:
+			continue
+		}
+		s, err := Load(path)
+		require.NoError(t, err, path)
+		// traces are resolved against directory of the scenario
+		for _, c := range s.Churn {
+			if len(c.Trace) != 0 {
+				_, err := os.Stat(c.Trace)
+				require.NoError(t, err, path)
+			}
+		}
+		for _, p := range s.Profiles {
+			if len(p.Trace) != 0 {
+				_, err := os.Stat(p.Trace)
+				require.NoError(t, err, path)
+			}
+		}
+	}
+}
+
+func TestLoadYAML(t *testing.T) {
+	dir, err := ioutil.TempDir("", "scenario-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "test.yaml")
+	require.NoError(t, ioutil.WriteFile(path, []byte(`
+name: test
+steps:
+  - boot: 1
+    relay: 3
+    node_config:
+      relay:
+        MaxPeers: 10
+        WhisperConfig:
+          MinimumPoW: 0.002
+  - users: 2
+conditions:
+  - types: [relay]
+    latency: 50
+  - types: [user]
+    preset: edge
+    packet_loss: 5
+churn:
+  - rate: 0.5
+    period: 10s
+workload:
+  type: rtt
+  sender: 0
+  receiver: 1
+duration: 1m30s
+`), 0644))
+	s, err := Load(path)
+	require.NoError(t, err)
+	require.Len(t, s.Steps, 2)
+	require.Equal(t, 3, s.Steps[0].Relay)
+	require.JSONEq(t, `{"MaxPeers": 10, "WhisperConfig": {"MinimumPoW": 0.002}}`, string(s.Steps[0].NodeConfig[cluster.Relay]))
+	require.Equal(t, []cluster.PeerType{cluster.Relay}, s.Conditions[0].Types)
+	require.Equal(t, []string{"10.0.0.0/24"}, s.Conditions[0].Options("10.0.0.0/24").TargetAddrs)
+	edge := s.Conditions[1].Options("10.0.0.0/24")
+	require.Equal(t, network.Presets["edge"].Options.Latency, edge.Latency)
+	require.Equal(t, 5, edge.PacketLoss)
+	require.Equal(t, 10*time.Second, s.Churn[0].Period.Duration)
+	require.Equal(t, 90*time.Second, s.Duration.Duration)
+}
+
+func TestLoadJSONUnknownField(t *testing.T) {
+	dir, err := ioutil.TempDir("", "scenario-test-")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	path := filepath.Join(dir, "test.json")
+	require.NoError(t, ioutil.WriteFile(path, []byte(`{"name": "test", "steps": [{"relay": 1}], "duraton": "1m"}`), 0644))
+	_, err = Load(path)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "duraton")
+}
+
+func TestValidate(t *testing.T) {
+	for _, tc := range []struct {
+		desc     string
+		scenario Scenario
+	}{
+		{"NoSteps", Scenario{}},
+		{"UnknownType", Scenario{Steps: []Step{{Relay: 1}}, Conditions: []Conditions{{Types: []cluster.PeerType{"unknown"}}}}},
+		{"RTTNoUsers", Scenario{Steps: []Step{{Relay: 1}}, Workload: Workload{Type: WorkloadRTT, Receiver: 1}}},
+		{"UnknownColumns", Scenario{Steps: []Step{{Relay: 1}}, Metrics: []Metrics{{Columns: []string{"unknown"}}}}},
+		{"UnknownNodeConfig", Scenario{Steps: []Step{{Relay: 1, NodeConfig: map[cluster.PeerType]Patch{cluster.Relay: Patch(`{"MaxPers": 1}`)}}}}},
+		{"UnknownPreset", Scenario{Steps: []Step{{Relay: 1}}, Conditions: []Conditions{{Preset: "unknown"}}}},
+		{"ChurnRate", Scenario{Steps: []Step{{Users: 1}}, Churn: []Churn{{Rate: 2, Period: Duration{time.Minute}}}}},
+		{"ChurnTwoModels", Scenario{Steps: []Step{{Users: 1}}, Churn: []Churn{{Rate: 0.5, Period: Duration{time.Minute}, Trace: "sessions.csv"}}}},
+		{"ChurnMode", Scenario{Steps: []Step{{Users: 1}}, Churn: []Churn{{Rate: 0.5, Period: Duration{time.Minute}, Modes: []churn.Mode{"sleep"}}}}},
+		{"ChurnTargetedNoContacts", Scenario{Steps: []Step{{Users: 1}}, Churn: []Churn{{Rate: 0.5, Period: Duration{time.Minute}, History: &History{Strategy: churn.RequestTargeted}}}}},
+		{"ChurnNoOffline", Scenario{Steps: []Step{{Users: 1}}, Churn: []Churn{{Online: &Distribution{Type: "exponential", Mean: Duration{time.Minute}}}}}},
+		{"ChurnRatio", Scenario{Steps: []Step{{Relay: 1}}, Churn: []Churn{{Types: []cluster.PeerType{cluster.Relay}, Ratio: 2, Rate: 0.5, Period: Duration{time.Minute}}}}},
+		{"ChurnDistribution", Scenario{Steps: []Step{{Users: 1}}, Churn: []Churn{{
+			Online:  &Distribution{Type: "weibull", Scale: Duration{time.Minute}},
+			Offline: &Distribution{Type: "exponential", Mean: Duration{time.Minute}},
+		}}}},
+	} {
+		t.Run(tc.desc, func(t *testing.T) {
+			require.Error(t, tc.scenario.Validate())
+		})
+	}
+}
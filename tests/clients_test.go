@@ -16,7 +16,7 @@ import (
 	"github.com/status-im/status-scale/client"
 	"github.com/status-im/status-scale/cluster"
 	"github.com/status-im/status-scale/metrics"
-	"github.com/status-im/status-scale/utils"
+	"github.com/status-im/status-scale/topology"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 )
@@ -62,25 +62,22 @@ func TestClientsExample(t *testing.T) {
 	// FIXME(dshulyak) if addr is not provided comcast will use both iptables and ip6tables to insert mangle rules
 	// ip6tables fails in the container on my enviornment due to lack of kernel module
 	//require.NoError(t, c.EnableConditionsGloobally(context.TODO(), network.Options{TargetAddr: c.IPAM.String(), Latency: 50}))
-	churn := churn.NewChurnSim(c.GetUsers(), churn.Params{
+	churn := churn.NewChurnSim(c.GetNodes(cluster.User), churn.Params{
 		TargetAddrs: []string{c.IPAM.String()},
 		Period:      10 * time.Second,
 		ChurnRate:   0.1,
 	})
 	churnCtx, cancel := context.WithCancel(context.Background())
+	// all nodes are started after churn simulator was terminated
 	go func() {
-		assert.NoError(t, utils.PollImmediate(churnCtx, func(ctx context.Context) error {
-			return churn.Control(ctx)
-		}, 200*time.Millisecond, 180*time.Minute))
-		// start all nodes after churn simulator was terminated
-		// FIXME(dshulyak) Start is confusing, it starts nodes but stops simulating networking issues
-		// need a better name
-		log.Debug("starting nodes")
-		assert.NoError(t, churn.Start(context.Background()))
+		assert.NoError(t, churn.Run(churnCtx))
 	}()
 	rtt := client.NewRTTMeter(chat0, c.GetUser(0), c.GetUser(1))
-	// TODO(dshulyak) figure out how to measure distance between two peers.
-	// one way is to get peers from one of the user and do bf search from there to second user.
+	graph, err := topology.Crawl(context.TODO(), c.GetPeers(cluster.Relay, cluster.Mail, cluster.User))
+	require.NoError(t, err)
+	hops, err := topology.HopDistance(graph, c.GetUser(0).Peer, c.GetUser(1).Peer)
+	require.NoError(t, err)
+	log.Info("distance between users", "hops", hops, "diameter", graph.Diameter())
 	log.Debug("started metering latency")
 	rtt.MeterFor(1 * time.Minute)
 	cancel()
@@ -1,14 +1,17 @@
 package tests
 
 import (
+	"context"
 	"flag"
 	"os"
+	"path/filepath"
 	"strings"
 
 	docker "docker.io/go-docker"
 	"github.com/ethereum/go-ethereum/log"
 	"github.com/status-im/status-scale/cluster"
 	"github.com/status-im/status-scale/dockershim"
+	"github.com/status-im/status-scale/network"
 )
 
 var (
@@ -20,6 +23,9 @@ func init() {
 	flag.StringVar(&CONF.CIDR, "cidr", "10.0.200.0/24", "network cidr")
 	flag.StringVar(&CONF.Verbosity, "log", "info", "log level")
 	flag.BoolVar(&CONF.Keep, "keep", false, "keep cluster after tests")
+	flag.BoolVar(&CONF.Attach, "attach", false, "attach to a cluster with the same prefix that was kept by previous run, peers that are already running are reused")
+	flag.StringVar(&CONF.RunDir, "rundir", filepath.Join(os.TempDir(), "status-scale"), "directory for cluster state")
+	flag.StringVar(&CONF.Emulator, "emulator", "netem", "network emulator: netem or comcast")
 	flag.StringVar(&CONF.Statusd, "statusd", "statusteam/statusd-debug:latest", "image for status go with comcast")
 	flag.StringVar(&CONF.Bootnode, "bootnode", "statusteam/bootnode-debug:latest", "image for bootnode with comcast")
 	flag.StringVar(&CONF.Rendezvous, "rendezvous", "statusteam/rendezvous-debug:latest", "image for rendezvous with comcast")
@@ -39,6 +45,9 @@ type Config struct {
 	CIDR      string
 	Verbosity string
 	Keep      bool
+	Attach    bool
+	RunDir    string
+	Emulator  string
 
 	// images
 	Statusd    string
@@ -47,7 +56,7 @@ type Config struct {
 	Rendezvous string
 }
 
-func ClusterFromConfig() cluster.Cluster {
+func ClusterFromConfig() *cluster.Cluster {
 	client, err := docker.NewEnvClient()
 	if err != nil {
 		panic(err)
@@ -56,8 +65,19 @@ func ClusterFromConfig() cluster.Cluster {
 	if err != nil {
 		panic(err)
 	}
-	return cluster.NewCluster(
+	c := cluster.NewCluster(
 		CONF.Prefix, ipam, dockershim.NewShim(client),
 		CONF.Statusd, CONF.Client, CONF.Bootnode, CONF.Rendezvous, CONF.Keep,
 	)
+	c.RunDir = CONF.RunDir
+	c.Emulator, err = network.NewEmulator(CONF.Emulator)
+	if err != nil {
+		panic(err)
+	}
+	if CONF.Attach {
+		if err := c.Attach(context.TODO()); err != nil {
+			panic(err)
+		}
+	}
+	return c
 }
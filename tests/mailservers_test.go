@@ -60,13 +60,12 @@ func TestHistoryDownload(t *testing.T) {
 		require.NoError(t, group.Error())
 	}
 	log.Info("messages generated. started collecting requests stats", "took", time.Since(start))
-	mail := c.GetMail(0)
-	for _, latency := range []int{40, 140, 400} {
-		require.NoError(t, mail.EnableConditions(context.Background(), network.Options{
-			TargetAddrs: []string{c.GetUser(0).IP()},
-			Latency:     latency,
-			BW:          40000,
-		}))
+	// presets limit download of the user more than upload, history is downloaded from the mail server
+	user := c.GetUser(0)
+	for _, preset := range []string{"lte", "3g", "edge"} {
+		opts, err := network.PresetOptions(preset, c.GetMail(0).IP())
+		require.NoError(t, err)
+		require.NoError(t, user.EnableConditions(context.Background(), opts))
 		samples := make([]float64, 30)
 		for i := range samples {
 			start := time.Now()
@@ -83,12 +82,8 @@ func TestHistoryDownload(t *testing.T) {
 		require.NoError(t, err)
 		percentile99, err := stats.Percentile(samples, 99)
 		require.NoError(t, err)
-		log.Info("collected request stats", "latency", latency, "percentile 95", percentile95, "percentile 99", percentile99)
-		require.NoError(t, mail.DisableConditions(context.Background(), network.Options{
-			PacketLoss:  1,
-			TargetAddrs: []string{c.GetUser(0).IP()},
-			Latency:     latency,
-		}))
+		log.Info("collected request stats", "preset", preset, "percentile 95", percentile95, "percentile 99", percentile99)
+		require.NoError(t, user.DisableConditions(context.Background(), opts))
 	}
 	table := metrics.NewCompleteTab("container name", metrics.P2PColumns())
 	require.NoError(t, client.CollectMetrics(context.Background(), table, c.GetUsers(), nil))
@@ -0,0 +1,72 @@
+package tests
+
+import (
+	"context"
+	"fmt"
+	"math/rand"
+	"testing"
+	"time"
+
+	"github.com/ethereum/go-ethereum/common/hexutil"
+	"github.com/status-im/status-console-client/protocol/gethservice"
+	"github.com/status-im/status-scale/client"
+	"github.com/status-im/status-scale/cluster"
+	"github.com/stretchr/testify/require"
+)
+
+func received(ctx context.Context, chat client.Chat, contact gethservice.Contact, payload string) error {
+	msgs, err := chat.Messages(ctx, contact, 0)
+	if err != nil {
+		return err
+	}
+	for _, msg := range msgs {
+		if msg.Text == payload {
+			return nil
+		}
+	}
+	return fmt.Errorf("message %s wasn't received", payload)
+}
+
+func TestPartitionHeal(t *testing.T) {
+	c := ClusterFromConfig()
+
+	err := c.Create(context.TODO(), cluster.ScaleOpts{Boot: 1, Mails: 1, Relay: 4, Deploy: true})
+	defer c.Clean(context.TODO())
+	require.NoError(t, err)
+	require.NoError(t, c.Create(context.TODO(), cluster.ScaleOpts{Users: 2, Deploy: true}))
+
+	var (
+		user0 = client.ChatClient(c.GetUser(0).Rpc())
+		user1 = client.ChatClient(c.GetUser(1).Rpc())
+	)
+	name := make([]byte, 10)
+	_, err = rand.Read(name)
+	require.NoError(t, err)
+	chat := gethservice.Contact{Name: hexutil.Encode(name)}
+	require.NoError(t, user0.AddContact(context.TODO(), chat))
+	require.NoError(t, user1.AddContact(context.TODO(), chat))
+
+	// mail server stays with the sender, so that receiver can request history after healing
+	require.NoError(t, c.Partition(context.TODO(),
+		[]cluster.Node{c.GetRelay(0), c.GetRelay(1), c.GetMail(0), c.GetUser(0)},
+		[]cluster.Node{c.GetRelay(2), c.GetRelay(3), c.GetUser(1)},
+	))
+	payload := "sent during partition"
+	require.NoError(t, user0.Send(context.TODO(), chat, payload))
+	Consistently(t, func() error {
+		if err := received(context.TODO(), user1, chat, payload); err == nil {
+			return fmt.Errorf("message %s was received during partition", payload)
+		}
+		return nil
+	}, 20*time.Second, time.Second)
+
+	require.NoError(t, c.Heal(context.TODO()))
+	Eventually(t, func() error {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := user1.RequestAll(ctx); err != nil {
+			return err
+		}
+		return received(ctx, user1, chat, payload)
+	}, 2*time.Minute, 2*time.Second)
+}
@@ -0,0 +1,29 @@
+package tests
+
+import (
+	"context"
+	"testing"
+
+	"github.com/status-im/status-scale/cluster"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRollingUpgrade(t *testing.T) {
+	c := ClusterFromConfig()
+
+	err := c.Create(context.TODO(), cluster.ScaleOpts{Boot: 1, Relay: 4, Deploy: true})
+	defer c.Clean(context.TODO())
+	require.NoError(t, err)
+
+	before := map[string]string{}
+	for _, p := range c.GetRelays() {
+		before[p.UID()] = p.Enode()
+	}
+	// upgrade to the same image, replaced containers must keep ip and identity
+	require.NoError(t, c.Upgrade(context.TODO(), cluster.ByType(cluster.Relay), CONF.Statusd, 2))
+	relays := c.GetRelays()
+	require.Len(t, relays, len(before))
+	for _, p := range relays {
+		require.Equal(t, before[p.UID()], p.Enode())
+	}
+}
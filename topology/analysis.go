@@ -0,0 +1,71 @@
+package topology
+
+// Distances returns number of hops from src to every node. Unreachable nodes have distance -1.
+func (g *Graph) Distances(src int) []int {
+	dist := make([]int, g.Len())
+	for i := range dist {
+		dist[i] = -1
+	}
+	dist[src] = 0
+	queue := []int{src}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		for _, n := range g.Neighbours(current) {
+			if dist[n] == -1 {
+				dist[n] = dist[current] + 1
+				queue = append(queue, n)
+			}
+		}
+	}
+	return dist
+}
+
+// ShortestPath returns number of hops between a and b, or -1 if b is not reachable from a.
+func (g *Graph) ShortestPath(a, b int) int {
+	return g.Distances(a)[b]
+}
+
+// Diameter is the longest shortest path between any two connected nodes.
+func (g *Graph) Diameter() int {
+	diameter := 0
+	for i := 0; i < g.Len(); i++ {
+		for _, d := range g.Distances(i) {
+			if d > diameter {
+				diameter = d
+			}
+		}
+	}
+	return diameter
+}
+
+// Components returns connected components, every component is a sorted list of nodes.
+func (g *Graph) Components() [][]int {
+	var (
+		rst  [][]int
+		seen = make([]bool, g.Len())
+	)
+	for i := 0; i < g.Len(); i++ {
+		if seen[i] {
+			continue
+		}
+		var component []int
+		for n, d := range g.Distances(i) {
+			if d >= 0 {
+				seen[n] = true
+				component = append(component, n)
+			}
+		}
+		rst = append(rst, component)
+	}
+	return rst
+}
+
+// DegreeDistribution returns number of nodes for every degree.
+func (g *Graph) DegreeDistribution() map[int]int {
+	rst := map[int]int{}
+	for i := 0; i < g.Len(); i++ {
+		rst[g.Degree(i)]++
+	}
+	return rst
+}
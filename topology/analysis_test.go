@@ -0,0 +1,29 @@
+package topology
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestAnalysisLine(t *testing.T) {
+	g := Line(5)
+	require.Equal(t, 4, g.ShortestPath(0, 4))
+	require.Equal(t, 4, g.Diameter())
+	require.Equal(t, map[int]int{1: 2, 2: 3}, g.DegreeDistribution())
+	require.Len(t, g.Components(), 1)
+}
+
+func TestAnalysisDisconnected(t *testing.T) {
+	g := Ring(4)
+	isolated := g.AddNode("isolated")
+	tail := g.AddNode("tail")
+	g.Connect(3, tail)
+	require.Equal(t, -1, g.ShortestPath(0, isolated))
+	require.Equal(t, 3, g.ShortestPath(1, tail))
+	require.Equal(t, 3, g.Diameter())
+	require.Equal(t, [][]int{{0, 1, 2, 3, 5}, {4}}, g.Components())
+	idx, exist := g.Index("tail")
+	require.True(t, exist)
+	require.Equal(t, tail, idx)
+}
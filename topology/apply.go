@@ -0,0 +1,73 @@
+package topology
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/ethereum/go-ethereum/log"
+	"github.com/ethereum/go-ethereum/p2p/enode"
+
+	"github.com/status-im/status-scale/client"
+	"github.com/status-im/status-scale/cluster"
+	"github.com/status-im/status-scale/utils"
+)
+
+// NodeID returns enode id of the peer in the same format as p2p.PeerInfo.ID.
+func NodeID(p *cluster.Peer) (string, error) {
+	node, err := enode.ParseV4(p.Enode())
+	if err != nil {
+		return "", fmt.Errorf("invalid enode of %s: %v", p.UID(), err)
+	}
+	return node.ID().String(), nil
+}
+
+// Apply connects peers according to the graph, node i is peers[i].
+// Connections between peers that are not in the graph are removed.
+// Peers should be deployed with discovery disabled, otherwise discovery will add random connections.
+func Apply(ctx context.Context, g *Graph, peers []*cluster.Peer) error {
+	if g.Len() != len(peers) {
+		return fmt.Errorf("graph has %d nodes but %d peers were provided", g.Len(), len(peers))
+	}
+	ids := make(map[string]int, len(peers))
+	for i, p := range peers {
+		id, err := NodeID(p)
+		if err != nil {
+			return err
+		}
+		ids[id] = i
+		g.SetName(i, p.UID())
+	}
+	group := utils.NewGroup(ctx, len(peers))
+	for i := range peers {
+		i := i
+		group.Run(func(ctx context.Context) error {
+			admin := client.AdminClient(peers[i].Rpc())
+			current, err := admin.Peers(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to get peers of %s: %v", peers[i].UID(), err)
+			}
+			for _, info := range current {
+				j, known := ids[info.ID]
+				if !known || g.Connected(i, j) {
+					continue
+				}
+				log.Debug("removing peer", "peer", peers[i].UID(), "remote", peers[j].UID())
+				if _, err := admin.RemovePeer(ctx, peers[j].Enode()); err != nil {
+					return fmt.Errorf("failed to remove %s from %s: %v", peers[j].UID(), peers[i].UID(), err)
+				}
+			}
+			// only one side dials to avoid duplicate connections
+			for _, j := range g.Neighbours(i) {
+				if j < i {
+					continue
+				}
+				log.Debug("adding peer", "peer", peers[i].UID(), "remote", peers[j].UID())
+				if _, err := admin.AddPeer(ctx, peers[j].Enode()); err != nil {
+					return fmt.Errorf("failed to add %s to %s: %v", peers[j].UID(), peers[i].UID(), err)
+				}
+			}
+			return nil
+		})
+	}
+	return group.Error()
+}
@@ -0,0 +1,68 @@
+package topology
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/status-im/status-scale/client"
+	"github.com/status-im/status-scale/cluster"
+	"github.com/status-im/status-scale/utils"
+)
+
+// Crawl builds a graph of connections between peers using admin_peers.
+// Every peer is a node named with its UID. Remote peers that are not in the list
+// are added as separate nodes named with their enode id.
+func Crawl(ctx context.Context, peers []*cluster.Peer) (*Graph, error) {
+	g := NewGraph(len(peers))
+	ids := make(map[string]int, len(peers))
+	for i, p := range peers {
+		id, err := NodeID(p)
+		if err != nil {
+			return nil, err
+		}
+		ids[id] = i
+		g.SetName(i, p.UID())
+	}
+	var (
+		mu    sync.Mutex
+		group = utils.NewGroup(ctx, len(peers))
+	)
+	for i := range peers {
+		i := i
+		group.Run(func(ctx context.Context) error {
+			infos, err := client.AdminClient(peers[i].Rpc()).Peers(ctx)
+			if err != nil {
+				return fmt.Errorf("failed to get peers of %s: %v", peers[i].UID(), err)
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			for _, info := range infos {
+				j, exist := ids[info.ID]
+				if !exist {
+					j = g.AddNode(info.ID)
+					ids[info.ID] = j
+				}
+				g.Connect(i, j)
+			}
+			return nil
+		})
+	}
+	if err := group.Error(); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+// HopDistance returns number of hops between two peers in the crawled graph, or -1 if they are not connected.
+func HopDistance(g *Graph, a, b *cluster.Peer) (int, error) {
+	i, exist := g.Index(a.UID())
+	if !exist {
+		return 0, fmt.Errorf("peer %s is not in the graph", a.UID())
+	}
+	j, exist := g.Index(b.UID())
+	if !exist {
+		return 0, fmt.Errorf("peer %s is not in the graph", b.UID())
+	}
+	return g.ShortestPath(i, j), nil
+}
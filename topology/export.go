@@ -0,0 +1,94 @@
+package topology
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+)
+
+// WriteDOT writes graph in graphviz format.
+func WriteDOT(w io.Writer, g *Graph) error {
+	if _, err := fmt.Fprintln(w, "graph G {"); err != nil {
+		return err
+	}
+	for i := 0; i < g.Len(); i++ {
+		if _, err := fmt.Fprintf(w, "  %d [label=%s];\n", i, strconv.Quote(g.Name(i))); err != nil {
+			return err
+		}
+	}
+	for _, e := range g.Edges() {
+		if _, err := fmt.Fprintf(w, "  %d -- %d;\n", e.A, e.B); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintln(w, "}")
+	return err
+}
+
+type graphmlKey struct {
+	ID   string `xml:"id,attr"`
+	For  string `xml:"for,attr"`
+	Name string `xml:"attr.name,attr"`
+	Type string `xml:"attr.type,attr"`
+}
+
+type graphmlData struct {
+	Key   string `xml:"key,attr"`
+	Value string `xml:",chardata"`
+}
+
+type graphmlNode struct {
+	ID   string      `xml:"id,attr"`
+	Data graphmlData `xml:"data"`
+}
+
+type graphmlEdge struct {
+	Source string `xml:"source,attr"`
+	Target string `xml:"target,attr"`
+}
+
+type graphmlGraph struct {
+	ID          string        `xml:"id,attr"`
+	EdgeDefault string        `xml:"edgedefault,attr"`
+	Nodes       []graphmlNode `xml:"node"`
+	Edges       []graphmlEdge `xml:"edge"`
+}
+
+type graphml struct {
+	XMLName xml.Name     `xml:"graphml"`
+	XMLNS   string       `xml:"xmlns,attr"`
+	Keys    []graphmlKey `xml:"key"`
+	Graph   graphmlGraph `xml:"graph"`
+}
+
+// WriteGraphML writes graph in GraphML format. Node names are stored in the "name" attribute.
+func WriteGraphML(w io.Writer, g *Graph) error {
+	doc := graphml{
+		XMLNS: "http://graphml.graphdrawing.org/xmlns",
+		Keys:  []graphmlKey{{ID: "name", For: "node", Name: "name", Type: "string"}},
+		Graph: graphmlGraph{ID: "G", EdgeDefault: "undirected"},
+	}
+	for i := 0; i < g.Len(); i++ {
+		doc.Graph.Nodes = append(doc.Graph.Nodes, graphmlNode{
+			ID:   "n" + strconv.Itoa(i),
+			Data: graphmlData{Key: "name", Value: g.Name(i)},
+		})
+	}
+	for _, e := range g.Edges() {
+		doc.Graph.Edges = append(doc.Graph.Edges, graphmlEdge{
+			Source: "n" + strconv.Itoa(e.A),
+			Target: "n" + strconv.Itoa(e.B),
+		})
+	}
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
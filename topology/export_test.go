@@ -0,0 +1,35 @@
+package topology
+
+import (
+	"bytes"
+	"encoding/xml"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWriteDOT(t *testing.T) {
+	g := Line(3)
+	g.SetName(0, "relay_0")
+	buf := new(bytes.Buffer)
+	require.NoError(t, WriteDOT(buf, g))
+	require.Equal(t, `graph G {
+  0 [label="relay_0"];
+  1 [label="1"];
+  2 [label="2"];
+  0 -- 1;
+  1 -- 2;
+}
+`, buf.String())
+}
+
+func TestWriteGraphML(t *testing.T) {
+	g := Star(4)
+	buf := new(bytes.Buffer)
+	require.NoError(t, WriteGraphML(buf, g))
+	var doc graphml
+	require.NoError(t, xml.Unmarshal(buf.Bytes(), &doc))
+	require.Len(t, doc.Graph.Nodes, 4)
+	require.Len(t, doc.Graph.Edges, 3)
+	require.Equal(t, "n0", doc.Graph.Edges[0].Source)
+}
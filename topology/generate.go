@@ -0,0 +1,232 @@
+package topology
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Line connects every node with the next one.
+func Line(n int) *Graph {
+	g := NewGraph(n)
+	for i := 0; i < n-1; i++ {
+		g.Connect(i, i+1)
+	}
+	return g
+}
+
+// Ring is a line where last node is connected with the first one.
+func Ring(n int) *Graph {
+	g := Line(n)
+	if n > 2 {
+		g.Connect(n-1, 0)
+	}
+	return g
+}
+
+// Star connects every node with the first one.
+func Star(n int) *Graph {
+	g := NewGraph(n)
+	for i := 1; i < n; i++ {
+		g.Connect(0, i)
+	}
+	return g
+}
+
+// RandomRegular generates random graph where every node has exactly k neighbours.
+func RandomRegular(n, k int, seed int64) (*Graph, error) {
+	if k >= n || k < 0 {
+		return nil, fmt.Errorf("degree %d must be lower than number of nodes %d", k, n)
+	}
+	if n*k%2 != 0 {
+		return nil, fmt.Errorf("n*k must be even, got n=%d k=%d", n, k)
+	}
+	rng := rand.New(rand.NewSource(seed))
+	// pair points at random and restart if we can't find a valid pair.
+	// restarts are rare for small degrees.
+	for attempt := 0; attempt < 100; attempt++ {
+		g := NewGraph(n)
+		points := make([]int, 0, n*k)
+		for i := 0; i < n; i++ {
+			for j := 0; j < k; j++ {
+				points = append(points, i)
+			}
+		}
+		if pairPoints(g, points, rng) {
+			return g, nil
+		}
+	}
+	return nil, fmt.Errorf("failed to generate random regular graph with n=%d k=%d", n, k)
+}
+
+func pairPoints(g *Graph, points []int, rng *rand.Rand) bool {
+	for len(points) > 0 {
+		paired := false
+		for try := 0; try < 10*len(points); try++ {
+			i, j := rng.Intn(len(points)), rng.Intn(len(points))
+			a, b := points[i], points[j]
+			if a == b || g.Connected(a, b) {
+				continue
+			}
+			g.Connect(a, b)
+			if i < j {
+				i, j = j, i
+			}
+			points = append(points[:i], points[i+1:]...)
+			points = append(points[:j], points[j+1:]...)
+			paired = true
+			break
+		}
+		if !paired {
+			return false
+		}
+	}
+	return true
+}
+
+// SmallWorld generates Watts-Strogatz graph. Every node is connected to k nearest neighbours in a ring
+// and then every edge is rewired to a random node with probability beta.
+func SmallWorld(n, k int, beta float64, seed int64) (*Graph, error) {
+	if k%2 != 0 || k >= n || k < 2 {
+		return nil, fmt.Errorf("k must be even and in range [2, %d), got %d", n, k)
+	}
+	if beta < 0 || beta > 1 {
+		return nil, fmt.Errorf("beta must be in range [0, 1], got %v", beta)
+	}
+	rng := rand.New(rand.NewSource(seed))
+	g := NewGraph(n)
+	for i := 0; i < n; i++ {
+		for j := 1; j <= k/2; j++ {
+			g.Connect(i, (i+j)%n)
+		}
+	}
+	for j := 1; j <= k/2; j++ {
+		for i := 0; i < n; i++ {
+			if rng.Float64() >= beta {
+				continue
+			}
+			// node is connected to everyone
+			if g.Degree(i) == n-1 {
+				continue
+			}
+			w := rng.Intn(n)
+			for w == i || g.Connected(i, w) {
+				w = rng.Intn(n)
+			}
+			g.Disconnect(i, (i+j)%n)
+			g.Connect(i, w)
+		}
+	}
+	return g, nil
+}
+
+// ReadEdgeList parses a list of edges. Every line is a pair of node indexes separated by whitespace.
+// Empty lines and lines that start with # are ignored.
+func ReadEdgeList(r io.Reader) (*Graph, error) {
+	var (
+		edges   []Edge
+		max     = -1
+		scanner = bufio.NewScanner(r)
+		line    = 0
+	)
+	for scanner.Scan() {
+		line++
+		text := strings.TrimSpace(scanner.Text())
+		if len(text) == 0 || strings.HasPrefix(text, "#") {
+			continue
+		}
+		fields := strings.Fields(text)
+		if len(fields) != 2 {
+			return nil, fmt.Errorf("line %d: expected two nodes, got `%s`", line, text)
+		}
+		var edge [2]int
+		for i, f := range fields {
+			n, err := strconv.Atoi(f)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %v", line, err)
+			}
+			if n < 0 {
+				return nil, fmt.Errorf("line %d: negative node %d", line, n)
+			}
+			if n > max {
+				max = n
+			}
+			edge[i] = n
+		}
+		edges = append(edges, Edge{edge[0], edge[1]})
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if max < 0 {
+		return nil, errors.New("edge list is empty")
+	}
+	g := NewGraph(max + 1)
+	for _, e := range edges {
+		g.Connect(e.A, e.B)
+	}
+	return g, nil
+}
+
+func LoadEdgeList(path string) (*Graph, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadEdgeList(f)
+}
+
+const (
+	TypeLine          = "line"
+	TypeRing          = "ring"
+	TypeStar          = "star"
+	TypeRandomRegular = "random-regular"
+	TypeSmallWorld    = "small-world"
+	TypeFile          = "file"
+)
+
+// Spec describes a graph that can be built for arbitrary number of nodes.
+type Spec struct {
+	Type   string  `json:"type" yaml:"type"`
+	Degree int     `json:"degree" yaml:"degree"`
+	Beta   float64 `json:"beta" yaml:"beta"`
+	Seed   int64   `json:"seed" yaml:"seed"`
+	// Path to the edge list, required for file type.
+	Path string `json:"path" yaml:"path"`
+}
+
+func (s Spec) Build(n int) (*Graph, error) {
+	switch s.Type {
+	case TypeLine:
+		return Line(n), nil
+	case TypeRing:
+		return Ring(n), nil
+	case TypeStar:
+		return Star(n), nil
+	case TypeRandomRegular:
+		return RandomRegular(n, s.Degree, s.Seed)
+	case TypeSmallWorld:
+		return SmallWorld(n, s.Degree, s.Beta, s.Seed)
+	case TypeFile:
+		g, err := LoadEdgeList(s.Path)
+		if err != nil {
+			return nil, err
+		}
+		if g.Len() > n {
+			return nil, fmt.Errorf("edge list %s has %d nodes, only %d peers are available", s.Path, g.Len(), n)
+		}
+		// peers that are not mentioned in the edge list stay disconnected
+		full := NewGraph(n)
+		for _, e := range g.Edges() {
+			full.Connect(e.A, e.B)
+		}
+		return full, nil
+	}
+	return nil, fmt.Errorf("unknown topology %s", s.Type)
+}
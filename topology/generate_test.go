@@ -0,0 +1,67 @@
+package topology
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRing(t *testing.T) {
+	g := Ring(5)
+	require.Len(t, g.Edges(), 5)
+	for i := 0; i < g.Len(); i++ {
+		require.Equal(t, 2, g.Degree(i))
+	}
+	require.True(t, g.Connected(4, 0))
+}
+
+func TestStar(t *testing.T) {
+	g := Star(5)
+	require.Equal(t, 4, g.Degree(0))
+	require.Equal(t, []int{0}, g.Neighbours(3))
+}
+
+func TestRandomRegular(t *testing.T) {
+	g, err := RandomRegular(50, 4, 1)
+	require.NoError(t, err)
+	for i := 0; i < g.Len(); i++ {
+		require.Equal(t, 4, g.Degree(i))
+	}
+	same, err := RandomRegular(50, 4, 1)
+	require.NoError(t, err)
+	require.Equal(t, g.Edges(), same.Edges())
+
+	_, err = RandomRegular(5, 3, 1)
+	require.Error(t, err)
+}
+
+func TestSmallWorld(t *testing.T) {
+	g, err := SmallWorld(30, 4, 0, 1)
+	require.NoError(t, err)
+	require.Len(t, g.Edges(), 60)
+	require.True(t, g.Connected(0, 2))
+	require.True(t, g.Connected(29, 1))
+
+	rewired, err := SmallWorld(30, 4, 0.5, 1)
+	require.NoError(t, err)
+	require.Len(t, rewired.Edges(), 60)
+	require.NotEqual(t, g.Edges(), rewired.Edges())
+}
+
+func TestReadEdgeList(t *testing.T) {
+	g, err := ReadEdgeList(strings.NewReader(`
+# triangle with a tail
+0 1
+1 2
+2 0
+
+2 3
+`))
+	require.NoError(t, err)
+	require.Equal(t, 4, g.Len())
+	require.Equal(t, []Edge{{0, 1}, {0, 2}, {1, 2}, {2, 3}}, g.Edges())
+
+	_, err = ReadEdgeList(strings.NewReader("0 1 2"))
+	require.Error(t, err)
+}
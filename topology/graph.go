@@ -0,0 +1,107 @@
+package topology
+
+import (
+	"sort"
+	"strconv"
+)
+
+// Edge is an undirected connection between two nodes. A is always lower than B.
+type Edge struct {
+	A, B int
+}
+
+func NewGraph(n int) *Graph {
+	g := &Graph{
+		names: make([]string, n),
+		adj:   make([]map[int]struct{}, n),
+	}
+	for i := range g.adj {
+		g.adj[i] = map[int]struct{}{}
+	}
+	return g
+}
+
+// Graph is an undirected graph without self loops and parallel edges.
+type Graph struct {
+	names []string
+	adj   []map[int]struct{}
+}
+
+func (g *Graph) Len() int {
+	return len(g.adj)
+}
+
+// Name returns a name of the node. If name is not set index is used instead.
+func (g *Graph) Name(i int) string {
+	if len(g.names[i]) == 0 {
+		return strconv.Itoa(i)
+	}
+	return g.names[i]
+}
+
+func (g *Graph) SetName(i int, name string) {
+	g.names[i] = name
+}
+
+// AddNode appends a new node to the graph and returns its index.
+func (g *Graph) AddNode(name string) int {
+	g.names = append(g.names, name)
+	g.adj = append(g.adj, map[int]struct{}{})
+	return len(g.adj) - 1
+}
+
+// Index returns index of the node with the given name.
+func (g *Graph) Index(name string) (int, bool) {
+	for i := range g.names {
+		if g.Name(i) == name {
+			return i, true
+		}
+	}
+	return 0, false
+}
+
+// Connect adds an edge between a and b. Self loops are ignored.
+func (g *Graph) Connect(a, b int) {
+	if a == b {
+		return
+	}
+	g.adj[a][b] = struct{}{}
+	g.adj[b][a] = struct{}{}
+}
+
+func (g *Graph) Disconnect(a, b int) {
+	delete(g.adj[a], b)
+	delete(g.adj[b], a)
+}
+
+func (g *Graph) Connected(a, b int) bool {
+	_, exist := g.adj[a][b]
+	return exist
+}
+
+func (g *Graph) Degree(i int) int {
+	return len(g.adj[i])
+}
+
+// Neighbours returns sorted neighbours of the node.
+func (g *Graph) Neighbours(i int) []int {
+	rst := make([]int, 0, len(g.adj[i]))
+	for n := range g.adj[i] {
+		rst = append(rst, n)
+	}
+	sort.Ints(rst)
+	return rst
+}
+
+// Edges returns sorted list of edges.
+func (g *Graph) Edges() []Edge {
+	var rst []Edge
+	for a := range g.adj {
+		for _, b := range g.Neighbours(a) {
+			if a < b {
+				rst = append(rst, Edge{a, b})
+			}
+		}
+	}
+	return rst
+}
@@ -29,7 +29,6 @@ func PollImmediate(parent context.Context, f func(context.Context) error, period
 			return nil
 		}
 	}
-	return nil
 }
 
 func PollImmediateNoError(parent context.Context, f func(context.Context) error, period, timeout time.Duration) (err error) {
@@ -55,5 +54,4 @@ func PollImmediateNoError(parent context.Context, f func(context.Context) error,
 			return err
 		}
 	}
-	return nil
 }